@@ -0,0 +1,110 @@
+package benchmark
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupportedOperation is returned by New if the adapter
+// doesn't implement the capability the operation requires
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// NewAdapter creates a new logger adapter writing to out
+type NewAdapter func(out io.ReadWriter, conf Config) (Adapter, error)
+
+// Adapter is a logger adapter implementing the operations all loggers
+// must support. Adapters opt in to further operations by implementing
+// the according capability interfaces (see FieldsLogger, ChildLogger,
+// ContextLogger, TypesLogger, SampledLogger and HookLogger).
+// The methods are invoked concurrently
+type Adapter interface {
+	// Info logs an info message, it's also used by the payload operations
+	Info(msg string)
+
+	// InfoFmt logs a formatted info message
+	InfoFmt(msg string, data int)
+
+	// Error logs an error message
+	Error(msg string)
+
+	// InfoWithErrorStack logs an info message
+	// with a stack-traced error attached
+	InfoWithErrorStack(msg string, err error)
+
+	// InfoWith3 logs an info message with 3 data fields attached
+	InfoWith3(msg string, fields *Fields3)
+
+	// InfoWith10 logs an info message with 10 data fields attached
+	InfoWith10(msg string, fields *Fields10)
+
+	// InfoWith10Exist logs an info message through a logger
+	// with the 10 data fields of NewFields10 previously attached
+	InfoWith10Exist(msg string)
+
+	// Flush flushes and closes the logger returning the number of logs
+	// it dropped. It's invoked when the benchmark completes before
+	// the clock is stopped
+	Flush() (dropped uint64, err error)
+}
+
+// FnFlush flushes and closes a logger
+// returning the number of logs it dropped
+type FnFlush func() (dropped uint64, err error)
+
+// FieldsLogger is implemented by adapters
+// supporting the info_with_n operation
+type FieldsLogger interface {
+	// InfoWithN logs an info message
+	// with an arbitrary number of data fields attached
+	InfoWithN(msg string, fields FieldsN)
+}
+
+// ChildLogger is implemented by adapters
+// supporting the info_child_logger operation
+type ChildLogger interface {
+	// InfoChildLogger creates a child logger with the given fields
+	// and logs the given number of info messages through it
+	InfoChildLogger(msg string, fields FieldsN, messages int)
+}
+
+// ContextLogger is implemented by adapters
+// supporting the info_with_context operation
+type ContextLogger interface {
+	// InfoWithContext logs an info message retrieving the logger
+	// and the trace ID from a context created with NewTraceContext
+	// on every call
+	InfoWithContext(msg string)
+}
+
+// TypesLogger is implemented by adapters
+// supporting the info_with_types operation
+type TypesLogger interface {
+	// InfoWithTypes logs an info message
+	// with 6 data fields of non-primitive types attached
+	InfoWithTypes(msg string, fields *FieldsTypes)
+}
+
+// SampledLogger is implemented by adapters
+// supporting the info_sampled operation
+type SampledLogger interface {
+	// InfoSampled logs an info message through a logger
+	// emitting only 1 of SampleRate logs starting with the first one
+	InfoSampled(msg string)
+}
+
+// BinaryLogger is implemented by adapters
+// which may write binary records without a line terminator
+type BinaryLogger interface {
+	// Binary returns true if every write to the output is a single
+	// binary record instead of newline terminated records
+	Binary() bool
+}
+
+// HookLogger is implemented by adapters
+// supporting the info_with_hook operation
+type HookLogger interface {
+	// InfoWithHook logs an info message through a logger
+	// with a hook adding the hostname field
+	InfoWithHook(msg string)
+}
@@ -3,8 +3,8 @@ package benchmark
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
-	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,6 +34,14 @@ const (
 	// FieldMessage represents the name of the message field
 	FieldMessage = "message"
 
+	// FieldHostname represents the name of the field
+	// added by the hook of the info_with_hook operation
+	FieldHostname = "hostname"
+
+	// Hostname is the value of the field
+	// added by the hook of the info_with_hook operation
+	Hostname = "logbench.local"
+
 	// LogOperationInfo represents the name of an info-log operation
 	LogOperationInfo = "info"
 
@@ -60,6 +68,57 @@ const (
 	// involving 10 previously appended fields
 	LogOperationInfoWith10Exist = "info_with_10_exist"
 
+	// LogOperationInfoWithN represents the name of an info-log operation
+	// involving a configurable number of newly appended fields
+	LogOperationInfoWithN = "info_with_n"
+
+	// LogOperationInfoLarge1K represents the name of an info-log operation
+	// involving a 1 KiB message
+	LogOperationInfoLarge1K = "info_large_1k"
+
+	// LogOperationInfoLarge16K represents the name of an info-log operation
+	// involving a 16 KiB message
+	LogOperationInfoLarge16K = "info_large_16k"
+
+	// LogOperationInfoLarge1M represents the name of an info-log operation
+	// involving a 1 MiB message
+	LogOperationInfoLarge1M = "info_large_1m"
+
+	// LogOperationInfoEscape represents the name of an info-log operation
+	// involving a message requiring JSON escaping
+	LogOperationInfoEscape = "info_escape"
+
+	// LogOperationInfoUnicode represents the name of an info-log operation
+	// involving a message of multi-byte UTF-8 characters
+	LogOperationInfoUnicode = "info_unicode"
+
+	// LogOperationInfoInvalidUTF8 represents the name of an info-log
+	// operation involving a message containing invalid UTF-8
+	LogOperationInfoInvalidUTF8 = "info_invalid_utf8"
+
+	// LogOperationInfoChildLogger represents the name of an info-log
+	// operation involving the creation of a child logger with a configurable
+	// number of fields and a configurable number of messages logged through it
+	LogOperationInfoChildLogger = "info_child_logger"
+
+	// LogOperationInfoWithContext represents the name of an info-log
+	// operation involving the retrieval of the logger and the trace ID
+	// from a context.Context
+	LogOperationInfoWithContext = "info_with_context"
+
+	// LogOperationInfoWithTypes represents the name of an info-log operation
+	// involving newly appended fields of time, duration, byte-slice,
+	// IP address and error-slice types
+	LogOperationInfoWithTypes = "info_with_types"
+
+	// LogOperationInfoSampled represents the name of an info-log operation
+	// involving a logger emitting only 1 of SampleRate logs
+	LogOperationInfoSampled = "info_sampled"
+
+	// LogOperationInfoWithHook represents the name of an info-log operation
+	// involving a logger hook adding the hostname field
+	LogOperationInfoWithHook = "info_with_hook"
+
 	// TimeFormat defines the time logging format
 	TimeFormat = "2006-01-02T15:04:05.999999999-07:00"
 )
@@ -100,6 +159,36 @@ type Fields10 struct {
 	Value10 []float64
 }
 
+// FieldsTypes is a list of 6 fields of non-primitive types
+// and their according values
+type FieldsTypes struct {
+	Name1 string
+	Name2 string
+	Name3 string
+	Name4 string
+	Name5 string
+	Name6 string
+
+	// Value1 is logged as a timestamp
+	Value1 time.Time
+
+	// Value2 is logged as a duration
+	Value2 time.Duration
+
+	// Value3 is logged as raw bytes (as a string)
+	Value3 []byte
+
+	// Value4 is logged as binary data using the logger's
+	// native binary encoding (base64 or hex)
+	Value4 []byte
+
+	// Value5 is logged as an IP address
+	Value5 net.IP
+
+	// Value6 is logged as a list of errors
+	Value6 []error
+}
+
 // NewFields3 creates a new instance of a set of 3 fields
 func NewFields3() *Fields3 {
 	return &Fields3{
@@ -133,141 +222,303 @@ func NewFields10() *Fields10 {
 	}
 }
 
-// FnInfo represents an info logging callback function
-type FnInfo func(msg string)
+// NewFieldsTypes creates a new instance of a set of 6 fields
+// of non-primitive types
+func NewFieldsTypes() *FieldsTypes {
+	return &FieldsTypes{
+		Name1: "field_1_time", Value1: time.Date(
+			2020, time.March, 14, 15, 9, 26, 535897932, time.UTC,
+		),
+		Name2: "field_2_duration", Value2: 1500*time.Millisecond +
+			250*time.Microsecond,
+		Name3: "field_3_bytes", Value3: []byte("some raw bytes"),
+		Name4: "field_4_binary", Value4: []byte{
+			0xde, 0xad, 0xbe, 0xef, 0x00, 0xff,
+		},
+		Name5: "field_5_ip", Value5: net.IPv4(192, 0, 2, 1),
+		Name6: "field_6_errors", Value6: []error{
+			fmt.Errorf("first error"),
+			fmt.Errorf("second error"),
+		},
+	}
+}
 
-// FnInfoFmt represents a formatted info logging callback function
-type FnInfoFmt func(msg string, data int)
+// Config defines the benchmark configuration
+type Config struct {
+	// Encoding defines the output encoding of the loggers
+	Encoding Encoding
 
-// FnError represents an error logging callback function
-type FnError func(msg string)
+	// FieldsN defines the number of fields
+	// appended by the info_with_n operation
+	FieldsN int
 
-// FnInfoWithErrorStack represents an info logging callback function
-// with a stack-traced error attached
-type FnInfoWithErrorStack func(msg string, err error)
+	// FieldTypes defines the types the fields of the info_with_n operation
+	// are cycled through, all field types are used when empty
+	FieldTypes []FieldType
 
-// FnInfoWith3 represents an info logging callback function
-// with 3 data fields attached
-type FnInfoWith3 func(msg string, fields *Fields3)
+	// ChildFields defines the number of fields the child logger
+	// of the info_child_logger operation is created with
+	ChildFields int
 
-// FnInfoWith10 represents an info logging callback function
-// with 10 data fields attached
-type FnInfoWith10 func(msg string, fields *Fields10)
+	// ChildMessages defines the number of messages logged through
+	// the child logger of the info_child_logger operation
+	ChildMessages int
 
-// FnInfoWith10Exist represents an info logging callback function
-// with 10 previously attached data fields
-type FnInfoWith10Exist func(msg string)
+	// TimeEncoding defines the encoding of the time field of all loggers
+	TimeEncoding TimeEncoding
 
-// Setup defines the callback functions for all benchmarked cases
-type Setup struct {
-	Info               func(io.ReadWriter) (FnInfo, error)
-	InfoFmt            func(io.ReadWriter) (FnInfoFmt, error)
-	Error              func(io.ReadWriter) (FnError, error)
-	InfoWithErrorStack func(io.ReadWriter) (FnInfoWithErrorStack, error)
-	InfoWith3          func(io.ReadWriter) (FnInfoWith3, error)
-	InfoWith10         func(io.ReadWriter) (FnInfoWith10, error)
-	InfoWith10Exist    func(io.ReadWriter) (FnInfoWith10Exist, error)
+	// Clock, if not nil, provides the time of the logs instead of
+	// the wall clock, which makes the output reproducible
+	Clock func() time.Time
+
+	// ClockMode names the source of Clock, it's only reported
+	ClockMode ClockMode
 }
 
-func checkSetupImplementation(setup Setup) error {
-	vl := reflect.ValueOf(setup)
-	tp := reflect.TypeOf(setup)
-	for i := 0; i < vl.NumField(); i++ {
-		if vl.Field(i).IsNil() {
-			return fmt.Errorf(
-				"missing implementation for Setup.%s",
-				tp.Field(i).Name,
-			)
-		}
+// Now returns the time of a log according to the configured clock
+func (c Config) Now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
 	}
-	return nil
+	return time.Now()
 }
 
-// New creates a new benchmark instance also initializing the logger
+// DefaultConfig returns the default benchmark configuration
+func DefaultConfig() Config {
+	return Config{
+		Encoding:      EncodingJSON,
+		TimeEncoding:  TimeRFC3339Nano,
+		ClockMode:     ClockWall,
+		FieldsN:       10,
+		ChildFields:   3,
+		ChildMessages: 5,
+	}
+}
+
+// New creates a new benchmark instance also initializing the logger.
+// Returns an error wrapping ErrUnsupportedOperation if the adapter
+// doesn't support the operation
 func New(
 	out io.ReadWriter,
 	operation string,
-	setup Setup,
+	newAdapter NewAdapter,
+	conf Config,
 ) (*Benchmark, error) {
 	if out == nil {
 		out = os.Stdout
 	}
 
-	if err := checkSetupImplementation(setup); err != nil {
-		return nil, fmt.Errorf("invalid logger implementation: %w", err)
+	writeLog, err := newWriteLog(operation, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the output of all operations to make the encoded size
+	// of the records comparable
+	counter := &countingWriter{
+		ReadWriter:   out,
+		countRecords: operation == LogOperationInfoSampled,
+	}
+	adapter, err := newAdapter(counter, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := adapter.(BinaryLogger); ok {
+		counter.binary = b.Binary()
 	}
 
-	bench := new(Benchmark)
+	bench := &Benchmark{
+		out:     counter,
+		adapter: adapter,
+	}
+	if bench.writeLog, err = writeLog(adapter); err != nil {
+		// Release the logger
+		_, _ = adapter.Flush()
+		return nil, fmt.Errorf("%w: %q", err, operation)
+	}
+	return bench, nil
+}
 
+// newWriteLog returns the function creating the log writing function
+// of the given operation for a particular adapter
+func newWriteLog(
+	operation string,
+	conf Config,
+) (func(Adapter) (func(), error), error) {
 	switch operation {
 	case LogOperationInfo:
-		fn, err := setup.Info(out)
-		if err != nil {
-			return nil, err
-		}
-		bench.writeLog = func() { fn("information") }
+		return func(a Adapter) (func(), error) {
+			return func() { a.Info("information") }, nil
+		}, nil
+
+	case LogOperationInfoLarge1K,
+		LogOperationInfoLarge16K,
+		LogOperationInfoLarge1M,
+		LogOperationInfoEscape,
+		LogOperationInfoUnicode,
+		LogOperationInfoInvalidUTF8:
+		msg := PayloadMessage(operation)
+		return func(a Adapter) (func(), error) {
+			return func() { a.Info(msg) }, nil
+		}, nil
 
 	case LogOperationInfoFmt:
-		fn, err := setup.InfoFmt(out)
-		if err != nil {
-			return nil, err
-		}
-		bench.writeLog = func() { fn("information %d", 42) }
+		return func(a Adapter) (func(), error) {
+			return func() { a.InfoFmt("information %d", 42) }, nil
+		}, nil
 
 	case LogOperationInfoWithErrorStack:
-		fn, err := setup.InfoWithErrorStack(out)
-		if err != nil {
-			return nil, err
-		}
 		errVal := errors.New("error with stack trace")
-		bench.writeLog = func() { fn("information", errVal) }
+		return func(a Adapter) (func(), error) {
+			return func() { a.InfoWithErrorStack("information", errVal) }, nil
+		}, nil
 
 	case LogOperationError:
-		fn, err := setup.Error(out)
-		if err != nil {
-			return nil, err
-		}
-		bench.writeLog = func() { fn("error message") }
+		return func(a Adapter) (func(), error) {
+			return func() { a.Error("error message") }, nil
+		}, nil
 
 	case LogOperationInfoWith10Exist:
-		fn, err := setup.InfoWith10Exist(out)
-		if err != nil {
-			return nil, err
-		}
-		bench.writeLog = func() { fn("information") }
+		return func(a Adapter) (func(), error) {
+			return func() { a.InfoWith10Exist("information") }, nil
+		}, nil
 
 	case LogOperationInfoWith3:
-		fn, err := setup.InfoWith3(out)
-		if err != nil {
-			return nil, err
-		}
 		fields := NewFields3()
-		bench.writeLog = func() { fn("information", fields) }
+		return func(a Adapter) (func(), error) {
+			return func() { a.InfoWith3("information", fields) }, nil
+		}, nil
 
 	case LogOperationInfoWith10:
-		fn, err := setup.InfoWith10(out)
-		if err != nil {
-			return nil, err
-		}
 		fields := NewFields10()
-		bench.writeLog = func() { fn("information", fields) }
+		return func(a Adapter) (func(), error) {
+			return func() { a.InfoWith10("information", fields) }, nil
+		}, nil
 
-	default:
-		return nil, fmt.Errorf("unsupported operation: %q", operation)
+	case LogOperationInfoWithN:
+		if conf.FieldsN < 0 {
+			return nil, fmt.Errorf("invalid number of fields: %d", conf.FieldsN)
+		}
+		fields := NewFieldsN(conf.FieldsN, conf.FieldTypes)
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(FieldsLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() { l.InfoWithN("information", fields) }, nil
+		}, nil
+
+	case LogOperationInfoChildLogger:
+		if conf.ChildFields < 0 {
+			return nil, fmt.Errorf(
+				"invalid number of child logger fields: %d",
+				conf.ChildFields,
+			)
+		}
+		if conf.ChildMessages < 0 {
+			return nil, fmt.Errorf(
+				"invalid number of child logger messages: %d",
+				conf.ChildMessages,
+			)
+		}
+		fields := NewFieldsN(
+			conf.ChildFields,
+			[]FieldType{FieldTypeString},
+		)
+		messages := conf.ChildMessages
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(ChildLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() {
+				l.InfoChildLogger("information", fields, messages)
+			}, nil
+		}, nil
+
+	case LogOperationInfoWithTypes:
+		fields := NewFieldsTypes()
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(TypesLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() { l.InfoWithTypes("information", fields) }, nil
+		}, nil
+
+	case LogOperationInfoSampled:
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(SampledLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() { l.InfoSampled("information") }, nil
+		}, nil
+
+	case LogOperationInfoWithHook:
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(HookLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() { l.InfoWithHook("information") }, nil
+		}, nil
+
+	case LogOperationInfoWithContext:
+		return func(a Adapter) (func(), error) {
+			l, ok := a.(ContextLogger)
+			if !ok {
+				return nil, ErrUnsupportedOperation
+			}
+			return func() { l.InfoWithContext("information") }, nil
+		}, nil
 	}
-
-	return bench, nil
+	return nil, fmt.Errorf("unsupported operation: %q", operation)
 }
 
 // Benchmark is a log benchmark
 type Benchmark struct {
 	writeLog func()
+
+	out     *countingWriter
+	adapter Adapter
 }
 
 // Statistics are the statistics of the execution of a benchmark
 type Statistics struct {
 	TotalLogsWritten uint64
 	TotalTime        time.Duration
+
+	// TotalLogsEmitted is the number of records actually written
+	// to the output, it's only counted for sampled operations
+	TotalLogsEmitted uint64
+
+	// TotalBytesWritten is the number of bytes written to the output
+	TotalBytesWritten uint64
+
+	// TotalFlushTime is the time the final flush took,
+	// it's included in TotalTime
+	TotalFlushTime time.Duration
+
+	// TotalLogsDropped is the number of logs dropped by asynchronous writers
+	// that don't block when their buffer is full
+	TotalLogsDropped uint64
+
+	// FlushErr is the error returned by the final flush, if any
+	FlushErr error
+
+	// Unsupported is set if the logger doesn't support the operation,
+	// the configured encodings or the clock, the benchmark wasn't run then
+	Unsupported bool
+
+	// UnsupportedReason describes why the logger doesn't support
+	// the operation if Unsupported is set
+	UnsupportedReason string
+
+	// Memory holds the statistics of the memory watcher
+	// if one was running during the benchmark
+	Memory MemStats
 }
 
 // Run runs the benchmark
@@ -303,13 +554,27 @@ func (bench *Benchmark) Run(
 	}
 	wg.Wait()
 
+	// Flush the logger before stopping the clock
+	var dropped uint64
+	var flushErr error
+	flushStart := time.Now()
+	dropped, flushErr = bench.adapter.Flush()
+	timeFlush := time.Since(flushStart)
+
 	timeTotal := time.Since(start)
 
 	stats := Statistics{
-		TotalLogsWritten: atomic.LoadUint64(&logsWritten),
-		TotalTime:        timeTotal,
+		TotalLogsWritten:  atomic.LoadUint64(&logsWritten),
+		TotalTime:         timeTotal,
+		TotalFlushTime:    timeFlush,
+		TotalBytesWritten: atomic.LoadUint64(&bench.out.bytes),
+		TotalLogsDropped:  dropped,
+		FlushErr:          flushErr,
 	}
 	stats.TotalLogsWritten -= uint64(concurrentWriters)
+	if bench.out.countRecords {
+		stats.TotalLogsEmitted = atomic.LoadUint64(&bench.out.records)
+	}
 
 	return stats
 }
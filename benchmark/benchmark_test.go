@@ -1,72 +1,257 @@
 package benchmark_test
 
 import (
+	"bytes"
 	"fmt"
-	"reflect"
+	"io"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/globusdigital/logbench/benchmark"
 	"github.com/stretchr/testify/require"
 )
 
-func TestSetupFunctionTypes(t *testing.T) {
-	tp := reflect.TypeOf(benchmark.Setup{})
-	for i := 0; i < tp.NumField(); i++ {
-		fl := tp.Field(i)
-		require.Equal(
-			t,
-			reflect.Func,
-			fl.Type.Kind(),
-			"Setup.%s type is not a function",
-			fl.Name,
-		)
+// names returns the names of values as printed by fmt
+func names[T any](values []T) []string {
+	n := make([]string, len(values))
+	for i, v := range values {
+		n[i] = fmt.Sprint(v)
+	}
+	return n
+}
 
-		require.Equal(
-			t,
-			1,
-			fl.Type.NumIn(),
-			"Setup.%s must accept io.ReadWriter as its first argument",
-			fl.Name,
-		)
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		kind    string
+		names   []string
+		invalid string
+		parse   func(string) (interface{}, error)
+	}{
+		{
+			kind:    "field type",
+			names:   names(benchmark.FieldTypesAll()),
+			invalid: "complex128",
+			parse: func(n string) (interface{}, error) {
+				return benchmark.ParseFieldType(n)
+			},
+		},
+		{
+			kind:    "encoding",
+			names:   names(benchmark.EncodingsAll()),
+			invalid: "xml",
+			parse: func(n string) (interface{}, error) {
+				return benchmark.ParseEncoding(n)
+			},
+		},
+		{
+			kind:    "time encoding",
+			names:   names(benchmark.TimeEncodingsAll()),
+			invalid: "iso8601",
+			parse: func(n string) (interface{}, error) {
+				return benchmark.ParseTimeEncoding(n)
+			},
+		},
+		{
+			kind:    "clock mode",
+			names:   names(benchmark.ClockModesAll()),
+			invalid: "atomic",
+			parse: func(n string) (interface{}, error) {
+				return benchmark.ParseClockMode(n)
+			},
+		},
+	} {
+		t.Run(tt.kind, func(t *testing.T) {
+			for _, name := range tt.names {
+				actual, err := tt.parse(name)
+				require.NoError(t, err)
+				require.Equal(t, name, fmt.Sprint(actual))
+			}
 
-		require.Equal(
-			t,
-			1,
-			fl.Type.NumIn(),
-			"Setup.%s must accept io.ReadWriter as its first argument",
-			fl.Name,
-		)
+			_, err := tt.parse(tt.invalid)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.kind)
+			for _, name := range tt.names {
+				require.Contains(t, err.Error(), name)
+			}
+		})
+	}
+}
 
-		in1 := fl.Type.In(0)
-		require.True(
-			t,
-			fmt.Sprintf("%s.%s", in1.PkgPath(), in1.Name()) == "io.ReadWriter",
-			"Setup.%s must accept io.ReadWriter as its first argument",
-			fl.Name,
-		)
+func TestNewFieldsN(t *testing.T) {
+	types := []benchmark.FieldType{
+		benchmark.FieldTypeInt,
+		benchmark.FieldTypeTime,
+	}
+	fields := benchmark.NewFieldsN(5, types)
+	require.Len(t, fields, 5)
+
+	names := make(map[string]struct{}, len(fields))
+	for i, f := range fields {
+		require.Equal(t, types[i%len(types)], f.Type)
+		require.NotContains(t, names, f.Name, "duplicate field name")
+		names[f.Name] = struct{}{}
+	}
+}
+
+func TestSampler(t *testing.T) {
+	for _, n := range []uint64{0, 1, 2, 10} {
+		s := &benchmark.Sampler{N: n}
+		emitted := 0
+		for i := 0; i < 95; i++ {
+			if s.Sample() {
+				emitted++
+			}
+		}
+		if n < 1 {
+			n = 1
+		}
+		require.Equal(t, int((95+n-1)/n), emitted, "N: %d", n)
+	}
+}
 
+func TestAppendTime(t *testing.T) {
+	tm := time.Date(2021, time.February, 3, 4, 5, 6, 789000000, time.UTC)
+	for enc, expected := range map[benchmark.TimeEncoding]string{
+		benchmark.TimeRFC3339Nano: `"2021-02-03T04:05:06.789+00:00"`,
+		benchmark.TimeRFC3339:     `"2021-02-03T04:05:06+00:00"`,
+		benchmark.TimeUnix:        `1612325106`,
+		benchmark.TimeUnixMs:      `1612325106789`,
+		benchmark.TimeNone:        ``,
+	} {
 		require.Equal(
 			t,
-			2,
-			fl.Type.NumOut(),
-			"Setup.%s must return (func(...), error)",
-			fl.Name,
+			expected,
+			string(benchmark.AppendTime(nil, tm, enc)),
+			"encoding: %s",
+			enc,
 		)
+	}
+}
 
-		out1 := fl.Type.Out(0)
-		require.True(
-			t,
-			reflect.Func == out1.Kind() && out1.NumOut() == 0,
-			"Setup.%s must return (func(...), error)",
-			fl.Name,
-		)
+func TestNewClock(t *testing.T) {
+	start := time.Date(2021, time.February, 3, 4, 5, 6, 0, time.UTC)
 
-		out2 := fl.Type.Out(1)
-		require.True(
-			t,
-			out2.Kind() == reflect.Interface && out2.Name() == "error",
-			"Setup.%s must return (func(...), error)",
-			fl.Name,
+	require.Nil(t, benchmark.NewClock(benchmark.ClockWall, start))
+
+	fixed := benchmark.NewClock(benchmark.ClockFixed, start)
+	require.Equal(t, start, fixed())
+	require.Equal(t, start, fixed())
+
+	fake := benchmark.NewClock(benchmark.ClockFake, start)
+	require.Equal(t, start, fake())
+	require.Equal(t, start.Add(benchmark.FakeClockStep), fake())
+	require.Equal(t, start.Add(2*benchmark.FakeClockStep), fake())
+}
+
+// stubAdapter is an adapter of no-op logging methods
+// not implementing any capability
+type stubAdapter struct {
+	flush benchmark.FnFlush
+}
+
+func (stubAdapter) Info(string)                            {}
+func (stubAdapter) InfoFmt(string, int)                    {}
+func (stubAdapter) Error(string)                           {}
+func (stubAdapter) InfoWithErrorStack(string, error)       {}
+func (stubAdapter) InfoWith3(string, *benchmark.Fields3)   {}
+func (stubAdapter) InfoWith10(string, *benchmark.Fields10) {}
+func (stubAdapter) InfoWith10Exist(string)                 {}
+func (a stubAdapter) Flush() (uint64, error)               { return a.flush() }
+
+// newStubAdapter returns a constructor of stub adapters
+// using the given flush function
+func newStubAdapter(flush benchmark.FnFlush) benchmark.NewAdapter {
+	return func(io.ReadWriter, benchmark.Config) (benchmark.Adapter, error) {
+		return stubAdapter{flush: flush}, nil
+	}
+}
+
+func TestRunFlush(t *testing.T) {
+	flushes := 0
+	newAdapter := newStubAdapter(func() (uint64, error) {
+		flushes++
+		time.Sleep(time.Millisecond)
+		return 3, nil
+	})
+
+	bench, err := benchmark.New(
+		new(bytes.Buffer),
+		benchmark.LogOperationInfo,
+		newAdapter,
+		benchmark.DefaultConfig(),
+	)
+	require.NoError(t, err)
+	stats := bench.Run(10, 2, nil)
+	require.Equal(t, 1, flushes)
+	require.NoError(t, stats.FlushErr)
+	require.Equal(t, uint64(3), stats.TotalLogsDropped)
+	require.GreaterOrEqual(t, stats.TotalFlushTime, time.Millisecond)
+	require.GreaterOrEqual(t, stats.TotalTime, stats.TotalFlushTime)
+}
+
+func TestUnsupportedOperation(t *testing.T) {
+	for _, operation := range []string{
+		benchmark.LogOperationInfoWithN,
+		benchmark.LogOperationInfoChildLogger,
+		benchmark.LogOperationInfoWithContext,
+		benchmark.LogOperationInfoWithTypes,
+		benchmark.LogOperationInfoSampled,
+		benchmark.LogOperationInfoWithHook,
+	} {
+		flushes := 0
+		_, err := benchmark.New(
+			new(bytes.Buffer),
+			operation,
+			newStubAdapter(func() (uint64, error) {
+				flushes++
+				return 0, nil
+			}),
+			benchmark.DefaultConfig(),
 		)
+		require.ErrorIs(t, err, benchmark.ErrUnsupportedOperation, operation)
+		require.Equal(t, 1, flushes, operation)
+	}
+}
+
+func TestMemoryWatcher(t *testing.T) {
+	w := benchmark.StartMemoryWatcher(time.Millisecond, true)
+	var retained [][]byte
+	for i := 0; i < 100; i++ {
+		retained = append(retained, make([]byte, 64*1024))
+		time.Sleep(100 * time.Microsecond)
+	}
+	runtime.GC()
+	stats := w.Stop()
+	runtime.KeepAlive(retained)
+
+	require.NotZero(t, stats.StatSamples)
+	require.NotZero(t, stats.SamplingTime)
+	require.Greater(t, stats.WatchTime, stats.SamplingTime)
+	require.Greater(t, stats.MaxHeapAlloc, uint64(100*64*1024))
+	require.NotZero(t, stats.MaxHeapObjects)
+	require.NotZero(t, stats.MaxHeapLive)
+	require.GreaterOrEqual(t, stats.MaxHeapSys, stats.MaxHeapAlloc)
+	require.NotZero(t, stats.HeapSysInc)
+	require.NotZero(t, stats.GCCycles)
+	require.NotZero(t, stats.GCPauses)
+	require.NotZero(t, stats.GCPauseMax)
+	require.LessOrEqual(t, stats.GCPauseP99, stats.GCPauseMax)
+	require.Less(t, stats.SamplingOverhead(), 1.0)
+
+	require.Len(t, stats.Timeline, int(stats.StatSamples))
+	last := stats.Timeline[len(stats.Timeline)-1]
+	require.Equal(t, stats.GCCycles, last.GCCycles)
+	for i := 1; i < len(stats.Timeline); i++ {
+		require.GreaterOrEqual(t, stats.Timeline[i].Time, stats.Timeline[i-1].Time)
 	}
+
+	// Stopping again returns the same statistics
+	require.Equal(t, stats, w.Stop())
+
+	// Without a timeline only the statistics are kept
+	// including the initial and final samples
+	stats = benchmark.StartMemoryWatcher(time.Hour, false).Stop()
+	require.Equal(t, uint(2), stats.StatSamples)
+	require.Nil(t, stats.Timeline)
 }
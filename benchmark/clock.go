@@ -0,0 +1,72 @@
+package benchmark
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClockMode represents the source of the time of the logs
+type ClockMode string
+
+const (
+	// ClockWall represents the wall clock read by each logger
+	ClockWall ClockMode = "wall"
+
+	// ClockFixed represents a clock always returning the same time.
+	// Loggers call it instead of reading the wall clock
+	// and still format the time
+	ClockFixed ClockMode = "fixed"
+
+	// ClockFake represents a clock advancing by FakeClockStep
+	// on every reading
+	ClockFake ClockMode = "fake"
+)
+
+// FakeClockStep is the duration a fake clock advances by on every reading
+const FakeClockStep = time.Millisecond
+
+// ClockModesAll returns all supported clock modes
+func ClockModesAll() []ClockMode {
+	return []ClockMode{
+		ClockWall,
+		ClockFixed,
+		ClockFake,
+	}
+}
+
+// ParseClockMode parses the name of a clock mode
+func ParseClockMode(name string) (ClockMode, error) {
+	return parseName("clock mode", name, ClockModesAll(), stringName[ClockMode])
+}
+
+// NewClock creates a clock of the given mode starting at start
+// to be used as Config.Clock. Returns nil for the wall clock
+func NewClock(mode ClockMode, start time.Time) func() time.Time {
+	switch mode {
+	case ClockFixed:
+		return FixedClock(start)
+	case ClockFake:
+		return (&FakeClock{Start: start, Step: FakeClockStep}).Now
+	}
+	return nil
+}
+
+// FixedClock returns a clock always returning t
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// FakeClock is a clock advancing by Step on every reading,
+// it's safe for concurrent use
+type FakeClock struct {
+	Start time.Time
+	Step  time.Duration
+
+	readings int64
+}
+
+// Now returns Start advanced by Step for every previous reading
+func (c *FakeClock) Now() time.Time {
+	n := atomic.AddInt64(&c.readings, 1) - 1
+	return c.Start.Add(time.Duration(n) * c.Step)
+}
@@ -0,0 +1,31 @@
+package benchmark
+
+import "context"
+
+const (
+	// FieldTraceID represents the name of the trace ID field
+	FieldTraceID = "trace_id"
+
+	// TraceID is the trace ID carried by the context
+	// of the info_with_context operation
+	TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+type ctxKeyTraceID struct{}
+
+// ContextWithTraceID returns a copy of ctx carrying the given trace ID
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, ctxKeyTraceID{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx.
+// Returns an empty string if ctx doesn't carry any trace ID
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(ctxKeyTraceID{}).(string)
+	return traceID
+}
+
+// NewTraceContext creates a new context carrying TraceID
+func NewTraceContext() context.Context {
+	return ContextWithTraceID(context.Background(), TraceID)
+}
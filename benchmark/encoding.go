@@ -0,0 +1,36 @@
+package benchmark
+
+import "github.com/pkg/errors"
+
+// ErrUnsupportedEncoding is returned by loggers
+// not supporting the configured encoding
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+// Encoding represents a log output encoding
+type Encoding string
+
+const (
+	// EncodingJSON represents the JSON encoding
+	EncodingJSON Encoding = "json"
+
+	// EncodingConsole represents the human-readable console encoding
+	EncodingConsole Encoding = "console"
+
+	// EncodingLogfmt represents the logfmt encoding
+	// of space separated key=value pairs
+	EncodingLogfmt Encoding = "logfmt"
+)
+
+// EncodingsAll returns all supported encodings
+func EncodingsAll() []Encoding {
+	return []Encoding{
+		EncodingJSON,
+		EncodingConsole,
+		EncodingLogfmt,
+	}
+}
+
+// ParseEncoding parses the name of an encoding
+func ParseEncoding(name string) (Encoding, error) {
+	return parseName("encoding", name, EncodingsAll(), stringName[Encoding])
+}
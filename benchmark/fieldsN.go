@@ -0,0 +1,115 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FieldType represents the type of a field of a FieldsN list
+type FieldType int
+
+const (
+	// FieldTypeString represents a string field
+	FieldTypeString FieldType = iota
+
+	// FieldTypeInt represents an int field
+	FieldTypeInt
+
+	// FieldTypeFloat64 represents a float64 field
+	FieldTypeFloat64
+
+	// FieldTypeBool represents a bool field
+	FieldTypeBool
+
+	// FieldTypeDuration represents a time.Duration field
+	FieldTypeDuration
+
+	// FieldTypeTime represents a time.Time field
+	FieldTypeTime
+)
+
+// String returns the name of the field type
+func (t FieldType) String() string {
+	switch t {
+	case FieldTypeString:
+		return "string"
+	case FieldTypeInt:
+		return "int"
+	case FieldTypeFloat64:
+		return "float64"
+	case FieldTypeBool:
+		return "bool"
+	case FieldTypeDuration:
+		return "duration"
+	case FieldTypeTime:
+		return "time"
+	}
+	return "FieldType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// ParseFieldType parses the name of a field type
+func ParseFieldType(name string) (FieldType, error) {
+	return parseName("field type", name, FieldTypesAll(), FieldType.String)
+}
+
+// FieldTypesAll returns all supported field types
+func FieldTypesAll() []FieldType {
+	return []FieldType{
+		FieldTypeString,
+		FieldTypeInt,
+		FieldTypeFloat64,
+		FieldTypeBool,
+		FieldTypeDuration,
+		FieldTypeTime,
+	}
+}
+
+// Field is a single named and typed field of a FieldsN list.
+// Only the value matching Type is set
+type Field struct {
+	Name string
+	Type FieldType
+
+	String   string
+	Int      int
+	Float64  float64
+	Bool     bool
+	Duration time.Duration
+	Time     time.Time
+}
+
+// FieldsN is a list of an arbitrary number of fields
+type FieldsN []Field
+
+// FieldsNTime is the value of all time fields of a FieldsN list
+var FieldsNTime = time.Date(2020, time.March, 14, 15, 9, 26, 535897932, time.UTC)
+
+// NewFieldsN creates a new list of n fields cycling through the given types.
+// All field types are used if types is empty
+func NewFieldsN(n int, types []FieldType) FieldsN {
+	if len(types) < 1 {
+		types = FieldTypesAll()
+	}
+	fields := make(FieldsN, n)
+	for i := range fields {
+		f := &fields[i]
+		f.Type = types[i%len(types)]
+		f.Name = fmt.Sprintf("field_%d_%s", i+1, f.Type)
+		switch f.Type {
+		case FieldTypeString:
+			f.String = "some textual value"
+		case FieldTypeInt:
+			f.Int = 42 + i
+		case FieldTypeFloat64:
+			f.Float64 = 42.5 + float64(i)
+		case FieldTypeBool:
+			f.Bool = i%2 == 0
+		case FieldTypeDuration:
+			f.Duration = time.Duration(i+1) * time.Millisecond
+		case FieldTypeTime:
+			f.Time = FieldsNTime
+		}
+	}
+	return fields
+}
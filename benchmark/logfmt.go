@@ -0,0 +1,31 @@
+package benchmark
+
+import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogfmtNeedsQuoting returns true if s must be quoted
+// when written as a logfmt value
+func LogfmtNeedsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' ||
+			r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendLogfmtValue appends s to dst as a logfmt value
+// quoting it if necessary
+func AppendLogfmtValue(dst []byte, s string) []byte {
+	if !LogfmtNeedsQuoting(s) {
+		return append(dst, s...)
+	}
+	return strconv.AppendQuote(dst, s)
+}
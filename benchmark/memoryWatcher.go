@@ -1,67 +1,332 @@
 package benchmark
 
 import (
-	"context"
-	"runtime"
+	"math"
+	"runtime/metrics"
+	"sync"
 	"time"
 )
 
-// MemStats represents memory related statistics
+// Runtime metrics inspected by the memory watcher
+const (
+	metricHeapObjectsBytes = "/memory/classes/heap/objects:bytes"
+	metricHeapUnused       = "/memory/classes/heap/unused:bytes"
+	metricHeapFree         = "/memory/classes/heap/free:bytes"
+	metricHeapReleased     = "/memory/classes/heap/released:bytes"
+	metricHeapObjects      = "/gc/heap/objects:objects"
+	metricHeapLive         = "/gc/heap/live:bytes"
+	metricGCCycles         = "/gc/cycles/total:gc-cycles"
+	metricGCPauses         = "/sched/pauses/total/gc:seconds"
+	metricSchedLatencies   = "/sched/latencies:seconds"
+
+	// metricGCPausesLegacy is the deprecated name of metricGCPauses
+	// used by Go versions prior to 1.22
+	metricGCPausesLegacy = "/gc/pauses:seconds"
+)
+
+// MemSample is a sample of the memory usage taken by the memory watcher
+type MemSample struct {
+	// Time is the time the sample was taken at
+	// relative to the start of the watcher
+	Time time.Duration `json:"time_ns"`
+
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapObjects uint64 `json:"heap_objects"`
+	HeapLive    uint64 `json:"heap_live"`
+
+	// GCCycles is the number of GC cycles completed since the watcher started
+	GCCycles uint64 `json:"gc_cycles"`
+}
+
+// MemStats represents memory related statistics of a benchmark run
 type MemStats struct {
-	StatSamples    uint
+	// StatSamples is the number of times the metrics were sampled
+	StatSamples uint
+
+	// SamplingTime is the total time spent reading the metrics
+	SamplingTime time.Duration
+
+	// WatchTime is the time the watcher was running
+	WatchTime time.Duration
+
+	// HeapAllocInc, HeapObjectsInc and HeapSysInc count the samples
+	// raising the according maximum
 	HeapAllocInc   uint64
 	HeapObjectsInc uint64
 	HeapSysInc     uint64
-	MaxHeapAlloc   uint64
+
+	// MaxHeapAlloc is the maximum size of the heap objects
+	// including unreachable objects not yet freed
+	MaxHeapAlloc uint64
+
+	// MaxHeapObjects is the maximum number of heap objects
 	MaxHeapObjects uint64
-	MaxHeapSys     uint64
+
+	// MaxHeapSys is the maximum size of the memory obtained
+	// from the OS for the heap like runtime.MemStats.HeapSys
+	MaxHeapSys uint64
+
+	// MaxHeapLive is the maximum size of the live heap objects
+	// marked by the previous GC, 0 if no GC completed in the process yet
+	MaxHeapLive uint64
+
+	// GCCycles is the number of GC cycles completed during the run
+	GCCycles uint64
+
+	// GCPauses is the number of stop-the-world GC pauses during the run
+	GCPauses uint64
+
+	// GCPauseP99 and GCPauseMax are the 99th percentile and maximum
+	// of the GC pauses, approximated by the runtime's histogram buckets
+	GCPauseP99 time.Duration
+	GCPauseMax time.Duration
+
+	// SchedLatencyP99 and SchedLatencyMax are the 99th percentile
+	// and maximum of the time goroutines spent runnable before running
+	SchedLatencyP99 time.Duration
+	SchedLatencyMax time.Duration
+
+	// Timeline holds all samples in the order they were taken,
+	// it's only recorded if enabled when starting the watcher
+	Timeline []MemSample
 }
 
-// StartMemoryWatcher starts a memory watcher goroutine
-// which periodically writes memory statistics to the returned channel
-func StartMemoryWatcher(
-	ctx context.Context,
-	interval time.Duration,
-) (read chan MemStats) {
-	read = make(chan MemStats)
+// SamplingOverhead returns the share of the watch time
+// spent reading the metrics
+func (s MemStats) SamplingOverhead() float64 {
+	if s.WatchTime <= 0 {
+		return 0
+	}
+	return float64(s.SamplingTime) / float64(s.WatchTime)
+}
+
+// MemoryWatcher periodically samples the runtime metrics
+// while a benchmark is running
+type MemoryWatcher struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// timeline enables recording every sample in the timeline
+	timeline bool
+
+	start   time.Time
+	begin   []metrics.Sample
+	samples []metrics.Sample
+	stats   MemStats
+}
+
+// Indexes of the samples of the inspected metrics
+const (
+	sampleHeapObjectsBytes = iota
+	sampleHeapUnused
+	sampleHeapFree
+	sampleHeapReleased
+	sampleHeapObjects
+	sampleHeapLive
+	sampleGCCycles
+	sampleGCPauses
+	sampleSchedLatencies
+)
+
+// newSamples returns the samples of the inspected metrics
+func newSamples() []metrics.Sample {
+	samples := []metrics.Sample{
+		sampleHeapObjectsBytes: {Name: metricHeapObjectsBytes},
+		sampleHeapUnused:       {Name: metricHeapUnused},
+		sampleHeapFree:         {Name: metricHeapFree},
+		sampleHeapReleased:     {Name: metricHeapReleased},
+		sampleHeapObjects:      {Name: metricHeapObjects},
+		sampleHeapLive:         {Name: metricHeapLive},
+		sampleGCCycles:         {Name: metricGCCycles},
+		sampleGCPauses:         {Name: metricGCPauses},
+		sampleSchedLatencies:   {Name: metricSchedLatencies},
+	}
+	pauses := samples[sampleGCPauses : sampleGCPauses+1]
+	metrics.Read(pauses)
+	if pauses[0].Value.Kind() == metrics.KindBad {
+		pauses[0].Name = metricGCPausesLegacy
+	}
+	return samples
+}
+
+// uint64Value returns the value of an uint64 sample,
+// 0 if the metric isn't supported
+func uint64Value(s metrics.Sample) uint64 {
+	if s.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s.Value.Uint64()
+}
+
+// histogramDelta returns the histogram of the observations
+// recorded between the two samples of a cumulative histogram
+func histogramDelta(start, end metrics.Sample) *metrics.Float64Histogram {
+	if start.Value.Kind() != metrics.KindFloat64Histogram ||
+		end.Value.Kind() != metrics.KindFloat64Histogram {
+		return nil
+	}
+	s := start.Value.Float64Histogram()
+	e := end.Value.Float64Histogram()
+	delta := &metrics.Float64Histogram{
+		Counts:  make([]uint64, len(e.Counts)),
+		Buckets: e.Buckets,
+	}
+	for i, c := range e.Counts {
+		delta.Counts[i] = c - s.Counts[i]
+	}
+	return delta
+}
+
+// histogramCount returns the number of observations of the histogram
+func histogramCount(h *metrics.Float64Histogram) uint64 {
+	if h == nil {
+		return 0
+	}
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+// histogramQuantile returns the upper bound of the bucket
+// holding the q-quantile of the observations of a histogram of seconds.
+// The lower bound is returned for the unbounded last bucket
+func histogramQuantile(h *metrics.Float64Histogram, q float64) time.Duration {
+	total := histogramCount(h)
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, c := range h.Counts {
+		cumulative += c
+		if cumulative < rank {
+			continue
+		}
+		bound := h.Buckets[i+1]
+		if math.IsInf(bound, 1) {
+			bound = h.Buckets[i]
+		}
+		return time.Duration(bound * float64(time.Second))
+	}
+	return 0
+}
+
+// StartMemoryWatcher starts a memory watcher goroutine sampling
+// the runtime metrics at the given interval until it's stopped.
+// Every sample is recorded in the timeline if timeline is true.
+// Unlike runtime.ReadMemStats reading the metrics doesn't stop the world
+func StartMemoryWatcher(interval time.Duration, timeline bool) *MemoryWatcher {
+	w := &MemoryWatcher{
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		timeline: timeline,
+		begin:    newSamples(),
+		samples:  newSamples(),
+	}
+	metrics.Read(w.begin)
+	w.start = time.Now()
+
+	// Take an initial sample to include the start of the run
+	w.sample()
+
 	go func() {
+		defer close(w.done)
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
-		var stats MemStats
-		var runtimeStats runtime.MemStats
 		for {
 			// Wait for the next inspection
 			select {
-			case <-ctx.Done():
+			case <-w.stop:
 				return
 			case <-ticker.C:
-				stats.StatSamples++
-
-				// Inspect memory usage
-				runtime.ReadMemStats(&runtimeStats)
-
-				// Update stats if necessary
-				if runtimeStats.HeapAlloc > stats.MaxHeapAlloc {
-					stats.HeapAllocInc++
-					stats.MaxHeapAlloc = runtimeStats.HeapAlloc
-				}
-				if runtimeStats.HeapObjects > stats.MaxHeapObjects {
-					stats.HeapObjectsInc++
-					stats.MaxHeapObjects = runtimeStats.HeapObjects
-				}
-				if runtimeStats.HeapSys > stats.MaxHeapSys {
-					stats.HeapSysInc++
-					stats.MaxHeapSys = runtimeStats.HeapSys
-				}
-
-				// Try to pass stats to reader if any
-				select {
-				case read <- stats:
-				default:
-				}
+				w.sample()
 			}
 		}
 	}()
-	return
+	return w
+}
+
+// sample reads the runtime metrics and updates the statistics
+func (w *MemoryWatcher) sample() {
+	sampleStart := time.Now()
+	metrics.Read(w.samples)
+	w.stats.SamplingTime += time.Since(sampleStart)
+	w.stats.StatSamples++
+
+	// Update stats if necessary
+	heapAlloc := uint64Value(w.samples[sampleHeapObjectsBytes])
+	if heapAlloc > w.stats.MaxHeapAlloc {
+		w.stats.HeapAllocInc++
+		w.stats.MaxHeapAlloc = heapAlloc
+	}
+	heapObjects := uint64Value(w.samples[sampleHeapObjects])
+	if heapObjects > w.stats.MaxHeapObjects {
+		w.stats.HeapObjectsInc++
+		w.stats.MaxHeapObjects = heapObjects
+	}
+	heapSys := heapAlloc +
+		uint64Value(w.samples[sampleHeapUnused]) +
+		uint64Value(w.samples[sampleHeapFree]) +
+		uint64Value(w.samples[sampleHeapReleased])
+	if heapSys > w.stats.MaxHeapSys {
+		w.stats.HeapSysInc++
+		w.stats.MaxHeapSys = heapSys
+	}
+	heapLive := uint64Value(w.samples[sampleHeapLive])
+	if heapLive > w.stats.MaxHeapLive {
+		w.stats.MaxHeapLive = heapLive
+	}
+	if w.timeline {
+		w.stats.Timeline = append(w.stats.Timeline, MemSample{
+			Time:        sampleStart.Sub(w.start),
+			HeapAlloc:   heapAlloc,
+			HeapObjects: heapObjects,
+			HeapLive:    heapLive,
+			GCCycles: uint64Value(w.samples[sampleGCCycles]) -
+				uint64Value(w.begin[sampleGCCycles]),
+		})
+	}
+}
+
+// Stop stops the memory watcher and returns the statistics
+// of the period it was running. Subsequent calls return the same statistics
+func (w *MemoryWatcher) Stop() MemStats {
+	w.stopOnce.Do(w.finish)
+	return w.stats
+}
+
+// finish stops the sampling goroutine, takes the final sample
+// and completes the statistics
+func (w *MemoryWatcher) finish() {
+	close(w.stop)
+	<-w.done
+
+	// Take a final sample to include the end of the run
+	w.sample()
+	stats := &w.stats
+	stats.WatchTime = time.Since(w.start)
+	stats.GCCycles = uint64Value(w.samples[sampleGCCycles]) -
+		uint64Value(w.begin[sampleGCCycles])
+
+	pauses := histogramDelta(
+		w.begin[sampleGCPauses],
+		w.samples[sampleGCPauses],
+	)
+	stats.GCPauses = histogramCount(pauses)
+	stats.GCPauseP99 = histogramQuantile(pauses, 0.99)
+	stats.GCPauseMax = histogramQuantile(pauses, 1)
+
+	latencies := histogramDelta(
+		w.begin[sampleSchedLatencies],
+		w.samples[sampleSchedLatencies],
+	)
+	stats.SchedLatencyP99 = histogramQuantile(latencies, 0.99)
+	stats.SchedLatencyMax = histogramQuantile(latencies, 1)
 }
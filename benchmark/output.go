@@ -0,0 +1,39 @@
+package benchmark
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// countingWriter counts the bytes written to the underlying read-writer
+// and the records only if countRecords is set since counting them
+// adds to the measured time
+type countingWriter struct {
+	io.ReadWriter
+	bytes   uint64
+	records uint64
+
+	// countRecords is set for the operations reporting
+	// the number of records emitted
+	countRecords bool
+
+	// binary is set for binary records without a line terminator,
+	// every write is counted as a record then
+	binary bool
+}
+
+// Write implements the io.Writer interface
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ReadWriter.Write(p)
+	atomic.AddUint64(&w.bytes, uint64(n))
+	if w.countRecords {
+		records := uint64(1)
+		if !w.binary {
+			// Writes may hold multiple records or only part of one
+			records = uint64(bytes.Count(p[:n], []byte{'\n'}))
+		}
+		atomic.AddUint64(&w.records, records)
+	}
+	return n, err
+}
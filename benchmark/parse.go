@@ -0,0 +1,33 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseName returns the one of values named name.
+// The error lists the names of all values
+func parseName[T any](
+	kind string,
+	name string,
+	values []T,
+	nameOf func(T) string,
+) (T, error) {
+	names := make([]string, len(values))
+	for i, v := range values {
+		if nameOf(v) == name {
+			return v, nil
+		}
+		names[i] = nameOf(v)
+	}
+	var zero T
+	return zero, fmt.Errorf(
+		"unknown %s: %q (expected one of: %s)",
+		kind,
+		name,
+		strings.Join(names, ", "),
+	)
+}
+
+// stringName returns the name of a value of a string type
+func stringName[T ~string](v T) string { return string(v) }
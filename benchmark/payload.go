@@ -0,0 +1,66 @@
+package benchmark
+
+import "strings"
+
+const (
+	// MessageSize1K is the size of the message
+	// of the info_large_1k operation
+	MessageSize1K = 1 << 10
+
+	// MessageSize16K is the size of the message
+	// of the info_large_16k operation
+	MessageSize16K = 16 << 10
+
+	// MessageSize1M is the size of the message
+	// of the info_large_1m operation
+	MessageSize1M = 1 << 20
+
+	// MessageEscape is a message consisting of characters
+	// that require escaping in JSON strings
+	MessageEscape = "quote \" backslash \\ slash / newline \n " +
+		"carriage return \r tab \t backspace \b form feed \f " +
+		"control \x00 \x01 \x1f html <a href='x'>&amp;</a>"
+
+	// MessageUnicode is a message consisting of multi-byte UTF-8 characters
+	MessageUnicode = "Grüezi mitenand! Привет, мир! 你好，世界！ " +
+		"こんにちは 🌍🚀 \u2028 line separator \ufeff bom"
+
+	// MessageInvalidUTF8 is a message containing byte sequences
+	// that are not valid UTF-8
+	MessageInvalidUTF8 = "invalid \xff\xfe start byte, " +
+		"truncated \xe2\x82 sequence, overlong \xc0\xaf slash, " +
+		"surrogate \xed\xa0\x80 half"
+)
+
+const largeMessagePattern = "Lorem ipsum dolor sit amet, consectetur " +
+	"adipiscing elit, sed do eiusmod tempor incididunt ut labore. "
+
+// NewLargeMessage creates a new ASCII message of the given size in bytes
+// which doesn't require escaping in JSON strings
+func NewLargeMessage(size int) string {
+	if size < 1 {
+		return ""
+	}
+	n := size/len(largeMessagePattern) + 1
+	return strings.Repeat(largeMessagePattern, n)[:size]
+}
+
+// PayloadMessage returns the message logged by the given payload operation.
+// Returns an empty string if operation isn't a payload operation
+func PayloadMessage(operation string) string {
+	switch operation {
+	case LogOperationInfoLarge1K:
+		return NewLargeMessage(MessageSize1K)
+	case LogOperationInfoLarge16K:
+		return NewLargeMessage(MessageSize16K)
+	case LogOperationInfoLarge1M:
+		return NewLargeMessage(MessageSize1M)
+	case LogOperationInfoEscape:
+		return MessageEscape
+	case LogOperationInfoUnicode:
+		return MessageUnicode
+	case LogOperationInfoInvalidUTF8:
+		return MessageInvalidUTF8
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package benchmark
+
+import "sync/atomic"
+
+// SampleRate defines the sample rate of the info_sampled operation,
+// only 1 of SampleRate logs is emitted
+const SampleRate = 10
+
+// Sampler emits 1 of every N logs starting with the first one.
+// It's used by loggers lacking a built-in sampler
+type Sampler struct {
+	N       uint64
+	counter uint64
+}
+
+// Sample returns true if the next log should be emitted
+func (s *Sampler) Sample() bool {
+	if s.N <= 1 {
+		return true
+	}
+	return atomic.AddUint64(&s.counter, 1)%s.N == 1
+}
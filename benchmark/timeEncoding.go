@@ -0,0 +1,87 @@
+package benchmark
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupportedTimeEncoding is returned by loggers
+// not supporting the configured time encoding
+var ErrUnsupportedTimeEncoding = errors.New("unsupported time encoding")
+
+// TimeEncoding represents the encoding of the time field
+type TimeEncoding string
+
+const (
+	// TimeRFC3339Nano represents RFC 3339 timestamps
+	// with nanosecond precision formatted according to TimeFormat
+	TimeRFC3339Nano TimeEncoding = "rfc3339nano"
+
+	// TimeRFC3339 represents RFC 3339 timestamps with second precision
+	TimeRFC3339 TimeEncoding = "rfc3339"
+
+	// TimeUnix represents Unix timestamps in seconds
+	TimeUnix TimeEncoding = "unix"
+
+	// TimeUnixMs represents Unix timestamps in milliseconds
+	TimeUnixMs TimeEncoding = "unixms"
+
+	// TimeNone represents records without a time field
+	TimeNone TimeEncoding = "none"
+)
+
+// TimeFormatRFC3339 defines the time logging format of TimeRFC3339
+const TimeFormatRFC3339 = "2006-01-02T15:04:05-07:00"
+
+// TimeEncodingsAll returns all supported time encodings
+func TimeEncodingsAll() []TimeEncoding {
+	return []TimeEncoding{
+		TimeRFC3339Nano,
+		TimeRFC3339,
+		TimeUnix,
+		TimeUnixMs,
+		TimeNone,
+	}
+}
+
+// ParseTimeEncoding parses the name of a time encoding
+func ParseTimeEncoding(name string) (TimeEncoding, error) {
+	return parseName(
+		"time encoding",
+		name,
+		TimeEncodingsAll(),
+		stringName[TimeEncoding],
+	)
+}
+
+// Layout returns the layout of textual time encodings
+// and an empty string for all other encodings.
+// The zero value is treated as TimeRFC3339Nano
+func (e TimeEncoding) Layout() string {
+	switch e {
+	case TimeRFC3339:
+		return TimeFormatRFC3339
+	case TimeUnix, TimeUnixMs, TimeNone:
+		return ""
+	}
+	return TimeFormat
+}
+
+// AppendTime appends t encoded as a JSON value
+// according to the given time encoding.
+// Nothing is appended for TimeNone, callers leave out the time key
+func AppendTime(b []byte, t time.Time, e TimeEncoding) []byte {
+	switch e {
+	case TimeNone:
+		return b
+	case TimeUnix:
+		return strconv.AppendInt(b, t.Unix(), 10)
+	case TimeUnixMs:
+		return strconv.AppendInt(b, t.UnixMilli(), 10)
+	}
+	b = append(b, '"')
+	b = t.AppendFormat(b, e.Layout())
+	return append(b, '"')
+}
@@ -0,0 +1,13 @@
+//go:build binary_log
+
+package main
+
+// zerolog writes CBOR instead of JSON when built with the binary_log tag,
+// its adapters are thus registered as zerolog-cbor and zerolog-diode-cbor
+// to avoid confusing results
+func init() {
+	adapters["zerolog-cbor"] = adapters["zerolog"]
+	delete(adapters, "zerolog")
+	adapters["zerolog-diode-cbor"] = adapters["zerolog-diode"]
+	delete(adapters, "zerolog-diode")
+}
@@ -0,0 +1,325 @@
+//go:build binary_log
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	newCBORDecoder := func(r io.Reader) recordDecoder {
+		return &cborDecoder{r: bufio.NewReader(r)}
+	}
+	decoders["zerolog-cbor"] = newCBORDecoder
+	decoders["zerolog-diode-cbor"] = newCBORDecoder
+	baseLoggers["zerolog-diode-cbor"] = "zerolog-cbor"
+}
+
+// CBOR major types and tags written by zerolog,
+// see https://www.rfc-editor.org/rfc/rfc8949
+const (
+	cborMajorUnsignedInt = 0
+	cborMajorNegativeInt = 1
+	cborMajorByteString  = 2
+	cborMajorTextString  = 3
+	cborMajorArray       = 4
+	cborMajorMap         = 5
+	cborMajorTag         = 6
+	cborMajorSimple      = 7
+
+	cborInfoIndefinite = 31
+
+	cborTagEpochTime    = 1
+	cborTagNetworkAddr  = 260
+	cborTagEmbeddedJSON = 262
+	cborTagHexString    = 263
+)
+
+// cborDecoder decodes a sequence of CBOR records into the types
+// encoding/json produces. Tagged values are converted to their
+// textual JSON representation
+type cborDecoder struct {
+	r *bufio.Reader
+}
+
+// More reports whether there's another record to decode
+func (d *cborDecoder) More() bool {
+	_, err := d.r.Peek(1)
+	return err == nil
+}
+
+// Decode decodes the next record into v
+// which must be a *map[string]interface{}
+func (d *cborDecoder) Decode(v interface{}) error {
+	m, ok := v.(*map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unsupported decoding target: %T", v)
+	}
+	val, err := d.decodeValue()
+	if err != nil {
+		return err
+	}
+	if *m, ok = val.(map[string]interface{}); !ok {
+		return fmt.Errorf("unexpected record type: %T", val)
+	}
+	return nil
+}
+
+// readHead reads the head of the next data item returning its major type,
+// its additional information and its argument
+func (d *cborDecoder) readHead() (major, info byte, arg uint64, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b>>5, b&0x1f
+	var n int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	case info == cborInfoIndefinite:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid additional information: %d", info)
+	}
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(d.r, buf[8-n:]); err != nil {
+		return 0, 0, 0, err
+	}
+	return major, info, binary.BigEndian.Uint64(buf), nil
+}
+
+// readBytes reads the content of a byte or text string
+func (d *cborDecoder) readBytes(major, info byte, arg uint64) ([]byte, error) {
+	if info != cborInfoIndefinite {
+		b := make([]byte, arg)
+		_, err := io.ReadFull(d.r, b)
+		return b, err
+	}
+	var b []byte
+	for {
+		chunk, err := d.decodeItem()
+		if errors.Is(err, errBreak) {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c, ok := chunk.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid chunk of major type %d", major)
+		}
+		b = append(b, c...)
+	}
+}
+
+// errBreak is returned when a break stop code is encountered
+var errBreak = errors.New("break")
+
+func (d *cborDecoder) decodeValue() (interface{}, error) {
+	v, err := d.decodeItem()
+	if b, ok := v.([]byte); ok {
+		// Byte strings are treated like text strings
+		return string([]rune(string(b))), err
+	}
+	return v, err
+}
+
+// decodeItem decodes the next data item. Byte and text strings
+// are returned as []byte
+func (d *cborDecoder) decodeItem() (interface{}, error) {
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborMajorUnsignedInt:
+		return float64(arg), nil
+
+	case cborMajorNegativeInt:
+		return -1 - float64(arg), nil
+
+	case cborMajorByteString, cborMajorTextString:
+		return d.readBytes(major, info, arg)
+
+	case cborMajorArray:
+		list := []interface{}{}
+		for i := uint64(0); info == cborInfoIndefinite || i < arg; i++ {
+			v, err := d.decodeValue()
+			if errors.Is(err, errBreak) && info == cborInfoIndefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+
+	case cborMajorMap:
+		m := map[string]interface{}{}
+		for i := uint64(0); info == cborInfoIndefinite || i < arg; i++ {
+			k, err := d.decodeValue()
+			if errors.Is(err, errBreak) && info == cborInfoIndefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key type: %T", k)
+			}
+			if m[key], err = d.decodeValue(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case cborMajorTag:
+		return d.decodeTag(arg)
+
+	case cborMajorSimple:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return float64(float16ToFloat32(uint16(arg))), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		case cborInfoIndefinite:
+			return nil, errBreak
+		}
+		return nil, fmt.Errorf("unsupported simple value: %d", info)
+	}
+	return nil, fmt.Errorf("unsupported major type: %d", major)
+}
+
+// decodeTag decodes the content of a tagged data item
+func (d *cborDecoder) decodeTag(tag uint64) (interface{}, error) {
+	v, err := d.decodeItem()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case cborTagEpochTime:
+		secs, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("invalid epoch time: %T", v)
+		}
+		// Floating point timestamps of the current epoch are precise
+		// to about a quarter of a microsecond only
+		whole, frac := math.Modf(secs)
+		t := time.Unix(int64(whole), int64(frac*float64(time.Second)))
+		return t.Round(time.Microsecond).UTC().Format(time.RFC3339Nano), nil
+
+	case cborTagNetworkAddr:
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid network address: %T", v)
+		}
+		return net.IP(b).String(), nil
+
+	case cborTagEmbeddedJSON:
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid embedded JSON: %T", v)
+		}
+		var j interface{}
+		if err := json.Unmarshal(b, &j); err != nil {
+			return nil, fmt.Errorf("decoding embedded JSON: %w", err)
+		}
+		return j, nil
+
+	case cborTagHexString:
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid hex string: %T", v)
+		}
+		return hex.EncodeToString(b), nil
+	}
+
+	// Ignore unknown tags
+	if b, ok := v.([]byte); ok {
+		return string([]rune(string(b))), nil
+	}
+	return v, nil
+}
+
+// float16ToFloat32 converts an IEEE 754 half-precision float
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mant<<13)
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Subnormal
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
+
+func TestCBORDecoder(t *testing.T) {
+	// {"a": 1, "b": [-2, true, null], "c": "x"} followed by
+	// {_ "t": 1(1.5), "h": 263(h'0aff')}
+	in, err := hex.DecodeString(
+		"a3616101616283" + "21f5f6" + "61636178" +
+			"bf6174c1f93e00" + "6168d90107420aff" + "ff",
+	)
+	require.NoError(t, err)
+
+	var records []map[string]interface{}
+	dec := &cborDecoder{r: bufio.NewReader(bytes.NewReader(in))}
+	for dec.More() {
+		var fields map[string]interface{}
+		require.NoError(t, dec.Decode(&fields))
+		records = append(records, fields)
+	}
+	require.Equal(t, []map[string]interface{}{
+		{
+			"a": float64(1),
+			"b": []interface{}{float64(-2), true, nil},
+			"c": "x",
+		},
+		{
+			"t": "1970-01-01T00:00:01.5Z",
+			"h": "0aff",
+		},
+	}, records)
+}
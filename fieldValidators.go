@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+)
+
+// nativeValidators creates the validators of duration and time values
+// reflecting a logger's native encoding of them
+type nativeValidators struct {
+	Duration func(time.Duration) func(interface{}) error
+	Time     func(time.Time) func(interface{}) error
+}
+
+// durationNs validates durations encoded as integer nanoseconds
+func durationNs(d time.Duration) func(interface{}) error {
+	return validate.Int(int(d))
+}
+
+// durationMs validates durations encoded as floating point milliseconds
+func durationMs(d time.Duration) func(interface{}) error {
+	return validate.Float64(float64(d) / float64(time.Millisecond))
+}
+
+// loggerNativeValidators maps logger names to the validators
+// of the logger's native duration and time encodings
+var loggerNativeValidators = map[string]nativeValidators{
+	"zap":     {Duration: durationNs, Time: validate.TimeEqual},
+	"zerolog": {Duration: durationMs, Time: validate.TimeEqual},
+	"zerolog-cbor": {
+		Duration: durationMs,
+		// Times are encoded as floating point epoch timestamps
+		// and rounded to microseconds when decoded
+		Time: func(t time.Time) func(interface{}) error {
+			return validate.TimeEqual(t.Round(time.Microsecond))
+		},
+	},
+	"logrus": {Duration: durationNs, Time: validate.TimeEqual},
+	"phuslog": {
+		Duration: durationMs,
+		// Times are truncated to milliseconds
+		Time: func(t time.Time) func(interface{}) error {
+			return validate.TimeEqual(t.Truncate(time.Millisecond))
+		},
+	},
+	"reference": {Duration: durationNs, Time: validate.TimeEqual},
+	"stdlog":    {Duration: durationNs, Time: validate.TimeEqual},
+}
+
+// newTypesValidators creates the validators of the info_with_types
+// operation for each logger reflecting the logger's encoding choices
+func newTypesValidators(fields *benchmark.FieldsTypes) map[string]validate.FV {
+	errs := make([]string, len(fields.Value6))
+	for i, err := range fields.Value6 {
+		errs[i] = err.Error()
+	}
+	base64Val := base64.StdEncoding.EncodeToString(fields.Value4)
+	hexVal := hex.EncodeToString(fields.Value4)
+
+	validators := map[string]validate.FV{
+		"zap": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(base64Val),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.ErrorObjects(errs),
+		},
+		"zerolog": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(hexVal),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.Strings(errs),
+		},
+		"zerolog-cbor": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(hexVal),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.Strings(errs),
+		},
+		"logrus": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(base64Val),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			// encoding/json encodes error values as empty objects
+			fields.Name6: validate.EmptyObjects(len(errs)),
+		},
+		"phuslog": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(hexVal),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.Strings(errs),
+		},
+		"reference": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(hexVal),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.Strings(errs),
+		},
+		"stdlog": {
+			fields.Name3: validate.Text(string(fields.Value3)),
+			fields.Name4: validate.Text(base64Val),
+			fields.Name5: validate.Text(fields.Value5.String()),
+			fields.Name6: validate.Strings(errs),
+		},
+	}
+	for loggerName, fv := range validators {
+		native := loggerNativeValidators[loggerName]
+		fv[fields.Name1] = native.Time(fields.Value1)
+		fv[fields.Name2] = native.Duration(fields.Value2)
+	}
+	return validators
+}
+
+// newValidatorField creates the validator of the given field,
+// durations and times are only checked for their type
+// since their encoding differs by logger (see newNativeFieldValidators)
+func newValidatorField(f benchmark.Field) func(interface{}) error {
+	switch f.Type {
+	case benchmark.FieldTypeString:
+		return validate.Text(f.String)
+	case benchmark.FieldTypeInt:
+		return validate.Int(f.Int)
+	case benchmark.FieldTypeFloat64:
+		return validate.Float64(f.Float64)
+	case benchmark.FieldTypeBool:
+		return validate.Bool(f.Bool)
+	case benchmark.FieldTypeDuration:
+		return validate.Number
+	case benchmark.FieldTypeTime:
+		return validate.Time
+	}
+	return func(interface{}) error {
+		return fmt.Errorf("unsupported field type: %s", f.Type)
+	}
+}
+
+// newNativeFieldValidators creates the validators of the duration
+// and time fields of the given list for each logger comparing them
+// to the expected values in the logger's native encoding
+func newNativeFieldValidators(fields benchmark.FieldsN) map[string]validate.FV {
+	validators := make(map[string]validate.FV, len(loggerNativeValidators))
+	for loggerName, native := range loggerNativeValidators {
+		fv := validate.FV{}
+		for _, f := range fields {
+			switch f.Type {
+			case benchmark.FieldTypeDuration:
+				fv[f.Name] = native.Duration(f.Duration)
+			case benchmark.FieldTypeTime:
+				fv[f.Name] = native.Time(f.Time)
+			}
+		}
+		validators[loggerName] = fv
+	}
+	return validators
+}
+
+// newFieldValidators creates the field validators of all operations
+// as well as additional logger-specific validators by operation
+func newFieldValidators(conf benchmark.Config) (
+	fieldValidators map[string]validate.FV,
+	loggerValidators map[string]map[string]validate.FV,
+) {
+	fields3 := benchmark.NewFields3()
+	fields10 := benchmark.NewFields10()
+	fieldsN := benchmark.NewFieldsN(conf.FieldsN, conf.FieldTypes)
+
+	fieldValidators = map[string]validate.FV{
+		benchmark.LogOperationInfo: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information"),
+		},
+		benchmark.LogOperationInfoFmt: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information 42"),
+		},
+		benchmark.LogOperationInfoWithErrorStack: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information"),
+			benchmark.FieldError:   validate.Text("error with stack trace"),
+		},
+		benchmark.LogOperationError: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelError),
+			benchmark.FieldMessage: validate.Text("error message"),
+		},
+		benchmark.LogOperationInfoWith3: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information"),
+			fields3.Name1:          validate.Text(fields3.Value1),
+			fields3.Name2:          validate.Int(fields3.Value2),
+			fields3.Name3:          validate.Float64(fields3.Value3),
+		},
+		benchmark.LogOperationInfoWith10: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information"),
+			fields10.Name1:         validate.Text(fields10.Value1),
+			fields10.Name2:         validate.Text(fields10.Value2),
+			fields10.Name3:         validate.Text(fields10.Value3),
+			fields10.Name4:         validate.Bool(fields10.Value4),
+			fields10.Name5:         validate.Text(fields10.Value5),
+			fields10.Name6:         validate.Int(fields10.Value6),
+			fields10.Name7:         validate.Float64(fields10.Value7),
+			fields10.Name8:         validate.Strings(fields10.Value8),
+			fields10.Name9:         validate.Ints(fields10.Value9),
+			fields10.Name10:        validate.Float64s(fields10.Value10),
+		},
+		benchmark.LogOperationInfoWith10Exist: {
+			benchmark.FieldTime:    validate.Time,
+			benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.Text("information"),
+			fields10.Name1:         validate.Text(fields10.Value1),
+			fields10.Name2:         validate.Text(fields10.Value2),
+			fields10.Name3:         validate.Text(fields10.Value3),
+			fields10.Name4:         validate.Bool(fields10.Value4),
+			fields10.Name5:         validate.Text(fields10.Value5),
+			fields10.Name6:         validate.Int(fields10.Value6),
+			fields10.Name7:         validate.Float64(fields10.Value7),
+			fields10.Name8:         validate.Strings(fields10.Value8),
+			fields10.Name9:         validate.Ints(fields10.Value9),
+			fields10.Name10:        validate.Float64s(fields10.Value10),
+		},
+	}
+
+	validatorsN := validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+	}
+	for _, f := range fieldsN {
+		validatorsN[f.Name] = newValidatorField(f)
+	}
+	fieldValidators[benchmark.LogOperationInfoWithN] = validatorsN
+
+	fieldValidators[benchmark.LogOperationInfoWithTypes] = validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+	}
+
+	// loggerValidators maps operations to additional
+	// logger-specific validators
+	loggerValidators = map[string]map[string]validate.FV{
+		benchmark.LogOperationInfoWithN: newNativeFieldValidators(fieldsN),
+		benchmark.LogOperationInfoWithTypes: newTypesValidators(
+			benchmark.NewFieldsTypes(),
+		),
+	}
+
+	fieldValidators[benchmark.LogOperationInfoSampled] = validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+	}
+
+	fieldValidators[benchmark.LogOperationInfoWithHook] = validate.FV{
+		benchmark.FieldTime:     validate.Time,
+		benchmark.FieldLevel:    validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage:  validate.Text("information"),
+		benchmark.FieldHostname: validate.Text(benchmark.Hostname),
+	}
+
+	fieldValidators[benchmark.LogOperationInfoWithContext] = validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+		benchmark.FieldTraceID: validate.Text(benchmark.TraceID),
+	}
+
+	validatorsChild := validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+	}
+	for _, f := range benchmark.NewFieldsN(
+		conf.ChildFields,
+		[]benchmark.FieldType{benchmark.FieldTypeString},
+	) {
+		validatorsChild[f.Name] = newValidatorField(f)
+	}
+	fieldValidators[benchmark.LogOperationInfoChildLogger] = validatorsChild
+
+	for _, operation := range []string{
+		benchmark.LogOperationInfoLarge1K,
+		benchmark.LogOperationInfoLarge16K,
+		benchmark.LogOperationInfoLarge1M,
+		benchmark.LogOperationInfoEscape,
+		benchmark.LogOperationInfoUnicode,
+		benchmark.LogOperationInfoInvalidUTF8,
+	} {
+		fieldValidators[operation] = validate.FV{
+			benchmark.FieldTime:  validate.Time,
+			benchmark.FieldLevel: validate.Level(benchmark.LevelInfo),
+			benchmark.FieldMessage: validate.RoundTrip(
+				benchmark.PayloadMessage(operation),
+			),
+		}
+	}
+
+	// Validate the time field according to the time encoding
+	for _, fv := range fieldValidators {
+		switch conf.TimeEncoding {
+		case benchmark.TimeUnix, benchmark.TimeUnixMs:
+			fv[benchmark.FieldTime] = validate.Number
+		case benchmark.TimeNone:
+			delete(fv, benchmark.FieldTime)
+		}
+	}
+
+	return fieldValidators, loggerValidators
+}
+
+// operationValidators returns the field validators of the given operation
+// merged with the logger-specific validators of the given logger
+func operationValidators(
+	fieldValidators map[string]validate.FV,
+	loggerValidators map[string]map[string]validate.FV,
+	loggerName string,
+	operationName string,
+) (validate.FV, error) {
+	validators := fieldValidators[operationName]
+	ov, ok := loggerValidators[operationName]
+	if !ok {
+		return validators, nil
+	}
+	lv, ok := ov[baseLogger(loggerName)]
+	if !ok {
+		return nil, fmt.Errorf(
+			"missing %q validators for logger %q",
+			operationName,
+			loggerName,
+		)
+	}
+	merged := make(validate.FV, len(validators)+len(lv))
+	for field, validate := range validators {
+		merged[field] = validate
+	}
+	for field, validate := range lv {
+		merged[field] = validate
+	}
+	return merged, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+)
+
+// unescapedNames maps logger names to the reason the logger is known
+// to write field names as is instead of escaping them
+var unescapedNames = map[string]string{
+	"phuslog": "field names are appended to the record verbatim",
+}
+
+// phuslogEscapes are the characters making phuslog escape a string
+const phuslogEscapes = "\"<'\\\b\f\n\r\t"
+
+// unescapedControlChars returns true if phuslog writes s with unescaped
+// control characters which makes the record invalid JSON.
+// phuslog only escapes \b, \f, \n, \r and \t, it escapes \x00 only
+// if s contains another character it escapes
+func unescapedControlChars(s string) bool {
+	if strings.IndexFunc(s, func(r rune) bool {
+		return r < 0x20 && !strings.ContainsRune(phuslogEscapes, r) && r != 0
+	}) >= 0 {
+		return true
+	}
+	return strings.IndexByte(s, 0) >= 0 && !strings.ContainsAny(s, phuslogEscapes)
+}
+
+// needsEscaping returns true if s can't be written
+// as a JSON string without escaping.
+// Invalid UTF-8 is replaced when decoding and thus not considered
+func needsEscaping(s string) bool {
+	return strings.ContainsAny(s, `"\`) ||
+		strings.IndexFunc(s, func(r rune) bool { return r < 0x20 }) >= 0
+}
+
+// knownFuzzFailure returns the reason the logger is known to fail
+// for the given input or an empty string if it's expected to succeed
+func knownFuzzFailure(loggerName, msg, name, value string) string {
+	base := baseLogger(loggerName)
+	if reason, ok := invalidJSON[base][benchmark.LogOperationInfoEscape]; ok &&
+		(unescapedControlChars(msg) || unescapedControlChars(value)) {
+		return reason
+	}
+	if reason, ok := unescapedNames[base]; ok && needsEscaping(name) {
+		return reason
+	}
+	return ""
+}
+
+// checkInfoWith3 writes a record with the given message and fields
+// and makes sure it's valid JSON holding the exact message and fields.
+// Invalid UTF-8 is expected to be replaced by U+FFFD byte by byte
+func checkInfoWith3(
+	newAdapter benchmark.NewAdapter,
+	msg string,
+	fields *benchmark.Fields3,
+) error {
+	buf := new(SyncBuffer)
+	a, err := newAdapter(buf, benchmark.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	a.InfoWith3(msg, fields)
+	if _, err := a.Flush(); err != nil {
+		return err
+	}
+
+	out := buf.String()
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &record); err != nil {
+		return fmt.Errorf("invalid JSON: %w: %s", err, out)
+	}
+	// Some loggers leave out empty messages
+	v, ok := record[benchmark.FieldMessage]
+	if ok || msg != "" {
+		if err := validate.RoundTrip(msg)(v); err != nil {
+			return fmt.Errorf("unexpected message: %w: %s", err, out)
+		}
+	}
+	name := string([]rune(fields.Name1))
+	v, ok = record[name]
+	if !ok {
+		return fmt.Errorf("missing field %q: %s", name, out)
+	}
+	if err := validate.RoundTrip(fields.Value1)(v); err != nil {
+		return fmt.Errorf("unexpected value of field %q: %w: %s", name, err, out)
+	}
+	return nil
+}
+
+// FuzzInfoWith3 writes a record with an arbitrary message, field name
+// and value through every JSON logger and makes sure the record
+// is valid JSON holding the exact message, field name and value.
+// Inputs the logger is known to fail for must still make it fail
+func FuzzInfoWith3(f *testing.F) {
+	fields := benchmark.NewFields3()
+	f.Add(benchmark.LogOperationInfoWith3, fields.Name1, fields.Value1)
+	f.Add("", "", "")
+	f.Add(`say "hi"`, `"quoted"`, `back\slash`)
+	f.Add("multi\nline", "new\nline", "tab\tand\rreturn")
+	f.Add("<b>bold</b>", "<html>&amp;", "  ")
+	f.Add("grüße 🚀", "ünïcödé", "日本語 🚀")
+	f.Add("\x07bell", "\x00", "\x1f\x7f")
+	f.Add("bad\xfe", "invalid\xff", "utf-8\xc3\x28")
+
+	f.Fuzz(func(t *testing.T, msg, name, value string) {
+		// Invalid UTF-8 is replaced when decoding
+		switch string([]rune(name)) {
+		case benchmark.FieldTime,
+			benchmark.FieldLevel,
+			benchmark.FieldMessage,
+			fields.Name2,
+			fields.Name3:
+			// Duplicate keys don't round-trip
+			t.Skip()
+		}
+		in := *fields
+		in.Name1, in.Value1 = name, value
+
+		for loggerName, newAdapter := range adapters {
+			if _, ok := decoders[loggerName]; ok {
+				continue
+			}
+			err := checkInfoWith3(newAdapter, msg, &in)
+			if reason := knownFuzzFailure(loggerName, msg, name, value); reason != "" {
+				// Make sure the deviation is still present
+				if err == nil {
+					t.Errorf("logger %q: known failure no longer occurs: %s", loggerName, reason)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("logger %q: %s", loggerName, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenTime is the time of all logs written to the golden files
+var goldenTime = time.Date(2021, time.February, 3, 4, 5, 6, 789000000, time.UTC)
+
+// stackTrace matches the machine-specific stack trace
+// following the first line of a verbose error
+var stackTrace = regexp.MustCompile(`("errorVerbose":"[^"\\]*)(?:[^"\\]|\\.)*"`)
+
+// caller matches the caller zap annotates the records with,
+// its line changes whenever the adapter is edited
+var caller = regexp.MustCompile(`"caller":"[^"]*"`)
+
+// normalizeGolden replaces the parts of the output
+// not reproducible across machines or edits by placeholders
+func normalizeGolden(out string) string {
+	out = caller.ReplaceAllString(out, `"caller":"<caller>"`)
+	return stackTrace.ReplaceAllString(out, `$1\n<stack trace>"`)
+}
+
+// goldenPath returns the path of the golden file
+// of the given logger and operation
+func goldenPath(loggerName, operation string) string {
+	return filepath.Join("testdata", "golden", loggerName, operation+".golden")
+}
+
+// TestGolden compares the output of each logger and operation
+// byte-for-byte to the according golden file to detect format changes
+// of new logger versions. Run with -update to regenerate the golden files
+func TestGolden(t *testing.T) {
+	conf := benchmark.DefaultConfig()
+	conf.Clock = benchmark.FixedClock(goldenTime)
+	configureZerolog(t, conf)
+
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			for _, operation := range operationsAll {
+				if benchmark.PayloadMessage(operation) != "" {
+					// Payload operations only differ from info in size
+					continue
+				}
+				t.Run(operation, func(t *testing.T) {
+					buf := new(SyncBuffer)
+					bench, err := benchmark.New(
+						buf,
+						operation,
+						newAdapter,
+						conf,
+					)
+					if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+						t.Skipf("logger %q doesn't support the operation", loggerName)
+					}
+					require.NoError(t, err)
+					require.NoError(t, bench.Run(1, 1, nil).FlushErr)
+
+					out := normalizeGolden(buf.String())
+					path := goldenPath(loggerName, operation)
+					if *update {
+						require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+						require.NoError(t, os.WriteFile(path, []byte(out), 0o644))
+					}
+					expected, err := os.ReadFile(path)
+					require.NoError(t, err, "run with -update to create the golden file")
+					require.Equal(t, string(expected), out)
+				})
+			}
+		})
+	}
+}
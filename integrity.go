@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// integrityTarget is the maximum number of logs written
+	// per logger and operation during an integrity check
+	integrityTarget = 10_000
+
+	// integrityWriters is the minimum number of concurrent writers
+	// of an integrity check
+	integrityWriters = 32
+
+	// integrityMaxPayload limits the total size of the messages
+	// of the payload operations held in memory during an integrity check
+	integrityMaxPayload = 64 << 20
+)
+
+// integrityResult is the result of the integrity check
+// of a particular logger and operation
+type integrityResult struct {
+	Logger    string
+	Operation string
+	Report    validate.LineReport
+
+	// Overlaps is the number of writes to the output
+	// started while another write was in progress
+	Overlaps uint64
+}
+
+// Failed returns true if a record is corrupted or invalid
+func (r integrityResult) Failed() bool {
+	return r.Report.Corrupted > 0 || r.Report.Invalid > 0
+}
+
+// overlapWriter is a thread-safe in-memory output counting the writes
+// overlapping another write, which reveals loggers not synchronizing
+// the writes to their output
+type overlapWriter struct {
+	SyncBuffer
+	active   int32
+	overlaps uint64
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&w.active, 1) > 1 {
+		atomic.AddUint64(&w.overlaps, 1)
+	}
+	// Let the other writers run while the write is in progress
+	// to reveal overlaps even on a single CPU
+	runtime.Gosched()
+	n, err := w.SyncBuffer.Write(p)
+	atomic.AddInt32(&w.active, -1)
+	return n, err
+}
+
+// checkIntegrity runs each operation of each logger writing up to
+// integrityTarget logs with at least integrityWriters concurrent writers
+// into memory and checks that every line is a complete JSON record
+// with all the fields of the operation.
+// It also counts the overlapping writes of loggers not synchronizing
+// the writes to their output. Unsupported logger/operation pairs are skipped
+func checkIntegrity(
+	loggers []string,
+	operations []string,
+	conf benchmark.Config,
+	target uint64,
+	concWriters uint,
+) ([]integrityResult, error) {
+	if conf.Encoding != benchmark.EncodingJSON {
+		return nil, fmt.Errorf(
+			"%w: %s (integrity check requires json)",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+	if target > integrityTarget {
+		log.Printf(
+			"integrity check: limiting -t %d to %d",
+			target,
+			integrityTarget,
+		)
+		target = integrityTarget
+	}
+	if concWriters < integrityWriters {
+		log.Printf(
+			"integrity check: raising -w %d to %d",
+			concWriters,
+			integrityWriters,
+		)
+		concWriters = integrityWriters
+	}
+
+	fieldValidators, loggerValidators := newFieldValidators(conf)
+
+	var results []integrityResult
+	for _, loggerName := range loggers {
+		newAdapter, ok := adapters[loggerName]
+		if !ok {
+			return nil, fmt.Errorf("no adapter for logger %q", loggerName)
+		}
+		for _, operation := range operations {
+			target := target
+			if msg := benchmark.PayloadMessage(operation); msg != "" {
+				if n := uint64(integrityMaxPayload / len(msg)); target > n {
+					target = n
+				}
+			}
+
+			// Overlapping writes are serialized like writes to a file,
+			// records can only be corrupted by loggers writing them
+			// in multiple parts or modifying them while they're written
+			buf := new(overlapWriter)
+			bench, err := benchmark.New(buf, operation, newAdapter, conf)
+			if isUnsupported(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("adapter %q init: %w", loggerName, err)
+			}
+			if s := bench.Run(target, concWriters, nil); s.FlushErr != nil {
+				return nil, fmt.Errorf(
+					"flushing %q: %w",
+					loggerName,
+					s.FlushErr,
+				)
+			}
+
+			validators, err := operationValidators(
+				fieldValidators,
+				loggerValidators,
+				loggerName,
+				operation,
+			)
+			if err != nil {
+				return nil, err
+			}
+			report, err := validate.Lines(buf, validators.Validate)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"checking output of %q: %w",
+					loggerName,
+					err,
+				)
+			}
+			results = append(results, integrityResult{
+				Logger:    loggerName,
+				Operation: operation,
+				Report:    report,
+				Overlaps:  atomic.LoadUint64(&buf.overlaps),
+			})
+		}
+	}
+	return results, nil
+}
+
+// printIntegrityResults prints the results of the integrity check
+func printIntegrityResults(results []integrityResult) {
+	tb := tablewriter.NewWriter(os.Stdout)
+	tb.SetHeader([]string{
+		"logger",
+		"operation",
+		"lines",
+		"corrupted",
+		"invalid",
+		"unsync. writes",
+		"first error",
+	})
+	tb.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, r := range results {
+		firstErr := ""
+		if r.Report.FirstErr != nil {
+			firstErr = r.Report.FirstErr.Error()
+		}
+		tb.Append([]string{
+			r.Logger,
+			r.Operation,
+			strconv.Itoa(r.Report.Lines),
+			strconv.Itoa(r.Report.Corrupted),
+			strconv.Itoa(r.Report.Invalid),
+			strconv.FormatUint(r.Overlaps, 10),
+			firstErr,
+		})
+	}
+	tb.Render()
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/stretchr/testify/require"
+)
+
+// parseLogfmt parses a logfmt line into a map of values typed like
+// encoding/json would decode them, so that the JSON field validators
+// can be applied. Since logfmt is untyped, unquoted values are parsed
+// as booleans and numbers if possible and as strings otherwise,
+// quoted values are always strings. Values enclosed in brackets are
+// parsed as JSON arrays if possible and as space separated lists
+// (as printed by fmt) otherwise. Invalid UTF-8 is replaced by U+FFFD
+// byte by byte like encoding/json does
+func parseLogfmt(line string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for i := 0; i < len(line); {
+		// Skip whitespace
+		if line[i] == ' ' || line[i] == '\n' {
+			i++
+			continue
+		}
+
+		// Read key
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("missing key at %d", start)
+		}
+		if i >= len(line) || line[i] == ' ' {
+			// Bare keys represent true
+			fields[key] = true
+			continue
+		}
+		i++ // Skip '='
+
+		// Read value
+		if i < len(line) && line[i] == '"' {
+			start = i
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated quote at %d", start)
+			}
+			i++ // Skip closing '"'
+			val, err := strconv.Unquote(line[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("unquoting value of %q: %w", key, err)
+			}
+			if isLogfmtList(val) {
+				fields[key] = parseLogfmtList(val)
+			} else {
+				fields[key] = string([]rune(val))
+			}
+			continue
+		}
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\n' {
+			i++
+		}
+		if val := line[start:i]; isLogfmtList(val) {
+			fields[key] = parseLogfmtList(val)
+		} else {
+			fields[key] = parseLogfmtScalar(val)
+		}
+	}
+	return fields, nil
+}
+
+func isLogfmtList(val string) bool {
+	return len(val) > 1 && val[0] == '[' && val[len(val)-1] == ']'
+}
+
+func parseLogfmtList(val string) []interface{} {
+	var list []interface{}
+	if err := json.Unmarshal([]byte(val), &list); err == nil {
+		return list
+	}
+	items := strings.Fields(val[1 : len(val)-1])
+	list = make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = parseLogfmtScalar(item)
+	}
+	return list
+}
+
+func parseLogfmtScalar(val string) interface{} {
+	switch val {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(val, 64); err == nil {
+		return f
+	}
+	return string([]rune(val))
+}
+
+func TestParseLogfmt(t *testing.T) {
+	fields, err := parseLogfmt(
+		`time=2020-03-14T15:09:26Z level=info message="hello \"world\"" ` +
+			`empty="" n=42 f=42.5 b=false bare l="[a b 3]" j=["x",1] q="7"`,
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"time":    "2020-03-14T15:09:26Z",
+		"level":   "info",
+		"message": `hello "world"`,
+		"empty":   "",
+		"n":       float64(42),
+		"f":       42.5,
+		"b":       false,
+		"bare":    true,
+		"l":       []interface{}{"a", "b", float64(3)},
+		"j":       []interface{}{"x", float64(1)},
+		"q":       "7",
+	}, fields)
+
+	_, err = parseLogfmt(`message="unterminated`)
+	require.Error(t, err)
+}
+
+// logfmtDeviations maps logger names to the operations the logger's
+// logfmt output can't be validated for and the according reason
+var logfmtDeviations = map[string]map[string]string{
+	"logrus": {
+		benchmark.LogOperationInfoWithN: "time and duration values " +
+			"are printed using fmt",
+		benchmark.LogOperationInfoWithTypes: "values are printed using fmt",
+	},
+	"zap": {
+		benchmark.LogOperationInfoWithTypes: "arrays of objects " +
+			"are written as [{k=v}]",
+	},
+	"phuslog": {
+		benchmark.LogOperationInfoEscape: "the underlying JSON is invalid",
+	},
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	conf := benchmark.DefaultConfig()
+	conf.Encoding = benchmark.EncodingLogfmt
+	fieldValidators, loggerValidators := newFieldValidators(conf)
+
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			for operationName := range fieldValidators {
+				t.Run(operationName, func(t *testing.T) {
+					buf := new(SyncBuffer)
+					bench, err := benchmark.New(
+						buf,
+						operationName,
+						newAdapter,
+						conf,
+					)
+					if errors.Is(err, benchmark.ErrUnsupportedEncoding) {
+						t.Skipf("logger %q doesn't support logfmt", loggerName)
+					}
+					if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+						t.Skipf("logger %q doesn't support the operation", loggerName)
+					}
+					require.NoError(t, err)
+					stats := bench.Run(1, 1, nil)
+					require.Equal(t, uint64(1), stats.TotalLogsWritten)
+
+					line, err := bufio.NewReader(buf).ReadString('\n')
+					require.NoError(t, err)
+					validators := validatorsOf(
+						t,
+						fieldValidators,
+						loggerValidators,
+						loggerName,
+						operationName,
+					)
+					fields, err := parseLogfmt(line)
+					if err != nil {
+						err = fmt.Errorf("parsing logfmt: %w", err)
+					} else {
+						err = validators.Validate(fields)
+					}
+
+					deviations := logfmtDeviations[baseLogger(loggerName)]
+					if reason, ok := deviations[operationName]; ok {
+						// Make sure the deviation is still present
+						require.Error(
+							t,
+							err,
+							"logger %q was expected to deviate: %s",
+							loggerName,
+							reason,
+						)
+						return
+					}
+					require.NoError(
+						t,
+						err,
+						"invalid record of logger %q",
+						loggerName,
+					)
+				})
+			}
+		})
+	}
+}
@@ -1,92 +1,162 @@
 package logrus
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/globusdigital/logbench/benchmark"
 	"github.com/sirupsen/logrus"
 )
 
-func newLogger(out io.ReadWriter) *logrus.Logger {
+// adapter is the logrus logger adapter
+type adapter struct {
+	l *logrus.Logger
+
+	// with10Exist and sampler are derived from l
+	with10Exist *logrus.Entry
+	sampler     *benchmark.Sampler
+	ctx         context.Context
+
+	// hooked is a separate logger since logrus hooks
+	// can't be added to entries
+	hooked *logrus.Logger
+}
+
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+)
+
+// timeFormatter is a formatter wrapper setting the time of the entries
+// to the time provided by the clock of the benchmark configuration
+// and adding the time field of the Unix time encodings
+// the logrus formatters don't support
+type timeFormatter struct {
+	logrus.Formatter
+	now      func() time.Time
+	encoding benchmark.TimeEncoding
+}
+
+func (f timeFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if f.now != nil {
+		// logrus still reads the wall clock when creating the entry,
+		// its time is only replaced
+		e.Time = f.now()
+	}
+	switch f.encoding {
+	case benchmark.TimeUnix:
+		e.Data[benchmark.FieldTime] = e.Time.Unix()
+	case benchmark.TimeUnixMs:
+		e.Data[benchmark.FieldTime] = e.Time.UnixMilli()
+	}
+	return f.Formatter.Format(e)
+}
+
+func newLogger(out io.ReadWriter, conf benchmark.Config) *logrus.Logger {
+	fieldMap := logrus.FieldMap{
+		"msg": "message",
+	}
+	unixTime := conf.TimeEncoding == benchmark.TimeUnix ||
+		conf.TimeEncoding == benchmark.TimeUnixMs
+	if unixTime {
+		// Keep the time field added by timeFormatter from being renamed
+		// to fields.time as it would clash with the disabled timestamp
+		fieldMap["time"] = "logrus_time"
+	}
+	disableTimestamp := unixTime || conf.TimeEncoding == benchmark.TimeNone
+	layout := conf.TimeEncoding.Layout()
+
 	l := logrus.New()
-	l.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: benchmark.TimeFormat,
-		FieldMap: logrus.FieldMap{
-			"msg": "message",
-		},
-	})
+	switch conf.Encoding {
+	case benchmark.EncodingConsole:
+		l.SetFormatter(&logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			DisableTimestamp: disableTimestamp,
+			TimestampFormat:  layout,
+			FieldMap:         fieldMap,
+		})
+	case benchmark.EncodingLogfmt:
+		l.SetFormatter(&logrus.TextFormatter{
+			DisableColors:    true,
+			DisableSorting:   true,
+			QuoteEmptyFields: true,
+			FullTimestamp:    true,
+			DisableTimestamp: disableTimestamp,
+			TimestampFormat:  layout,
+			FieldMap:         fieldMap,
+		})
+	default:
+		l.SetFormatter(&logrus.JSONFormatter{
+			DisableTimestamp: disableTimestamp,
+			TimestampFormat:  layout,
+			FieldMap:         fieldMap,
+		})
+	}
+	if conf.Clock != nil || unixTime {
+		l.SetFormatter(timeFormatter{
+			Formatter: l.Formatter,
+			now:       conf.Clock,
+			encoding:  conf.TimeEncoding,
+		})
+	}
 	l.SetOutput(out)
 	l.SetLevel(logrus.InfoLevel)
 	return l
 }
 
-func newInfo(out io.ReadWriter) (benchmark.FnInfo, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Info(msg)
-	}, nil
+func logrusFields(fields benchmark.FieldsN) logrus.Fields {
+	lf := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			lf[f.Name] = f.String
+		case benchmark.FieldTypeInt:
+			lf[f.Name] = f.Int
+		case benchmark.FieldTypeFloat64:
+			lf[f.Name] = f.Float64
+		case benchmark.FieldTypeBool:
+			lf[f.Name] = f.Bool
+		case benchmark.FieldTypeDuration:
+			lf[f.Name] = f.Duration
+		case benchmark.FieldTypeTime:
+			lf[f.Name] = f.Time
+		}
+	}
+	return lf
 }
 
-func newInfoFmt(out io.ReadWriter) (benchmark.FnInfoFmt, error) {
-	l := newLogger(out)
-	return func(msg string, data int) {
-		l.Infof(msg, data)
-	}, nil
+func (a *adapter) Info(msg string) {
+	a.l.Info(msg)
 }
 
-func newInfoWithErrorStack(out io.ReadWriter) (
-	benchmark.FnInfoWithErrorStack,
-	error,
-) {
-	l := newLogger(out)
-	return func(msg string, err error) {
-		l.WithError(err).Info(msg)
-	}, nil
+func (a *adapter) InfoFmt(msg string, data int) {
+	a.l.Infof(msg, data)
 }
 
-func newError(out io.ReadWriter) (benchmark.FnError, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Error(msg)
-	}, nil
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	a.l.WithError(err).Info(msg)
 }
 
-func newInfoWith3(out io.ReadWriter) (benchmark.FnInfoWith3, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields3) {
-		l.WithFields(logrus.Fields{
-			fields.Name1: fields.Value1,
-			fields.Name2: fields.Value2,
-			fields.Name3: fields.Value3,
-		}).Info(msg)
-	}, nil
+func (a *adapter) Error(msg string) {
+	a.l.Error(msg)
 }
 
-func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields10) {
-		l.WithFields(logrus.Fields{
-			fields.Name1:  fields.Value1,
-			fields.Name2:  fields.Value2,
-			fields.Name3:  fields.Value3,
-			fields.Name4:  fields.Value4,
-			fields.Name5:  fields.Value5,
-			fields.Name6:  fields.Value6,
-			fields.Name7:  fields.Value7,
-			fields.Name8:  fields.Value8,
-			fields.Name9:  fields.Value9,
-			fields.Name10: fields.Value10,
-		}).Info(msg)
-	}, nil
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	a.l.WithFields(logrus.Fields{
+		fields.Name1: fields.Value1,
+		fields.Name2: fields.Value2,
+		fields.Name3: fields.Value3,
+	}).Info(msg)
 }
 
-func newInfoWith10Exist(out io.ReadWriter) (
-	benchmark.FnInfoWith10Exist,
-	error,
-) {
-	l := newLogger(out)
-	fields := benchmark.NewFields10()
-	e := l.WithFields(logrus.Fields{
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	a.l.WithFields(logrus.Fields{
 		fields.Name1:  fields.Value1,
 		fields.Name2:  fields.Value2,
 		fields.Name3:  fields.Value3,
@@ -97,21 +167,110 @@ func newInfoWith10Exist(out io.ReadWriter) (
 		fields.Name8:  fields.Value8,
 		fields.Name9:  fields.Value9,
 		fields.Name10: fields.Value10,
-	})
-	return func(msg string) {
-		e.Info(msg)
-	}, nil
+	}).Info(msg)
 }
 
-// Setup defines the logrus logger setup
-func Setup() benchmark.Setup {
-	return benchmark.Setup{
-		Info:               newInfo,
-		InfoFmt:            newInfoFmt,
-		InfoWithErrorStack: newInfoWithErrorStack,
-		Error:              newError,
-		InfoWith3:          newInfoWith3,
-		InfoWith10:         newInfoWith10,
-		InfoWith10Exist:    newInfoWith10Exist,
+func (a *adapter) InfoWith10Exist(msg string) {
+	a.with10Exist.Info(msg)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	a.l.WithFields(logrusFields(fields)).Info(msg)
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
+) {
+	c := a.l.WithFields(logrusFields(fields))
+	for i := 0; i < messages; i++ {
+		c.Info(msg)
+	}
+}
+
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	a.l.WithFields(logrus.Fields{
+		fields.Name1: fields.Value1,
+		fields.Name2: fields.Value2,
+		fields.Name3: string(fields.Value3),
+		fields.Name4: fields.Value4,
+		fields.Name5: fields.Value5,
+		fields.Name6: fields.Value6,
+	}).Info(msg)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	// logrus doesn't provide a built-in sampler
+	if a.sampler.Sample() {
+		a.l.Info(msg)
+	}
+}
+
+// hostnameHook is a hook adding the hostname field
+type hostnameHook struct{}
+
+func (hostnameHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (hostnameHook) Fire(e *logrus.Entry) error {
+	e.Data[benchmark.FieldHostname] = benchmark.Hostname
+	return nil
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	a.hooked.Info(msg)
+}
+
+type ctxKeyLogger struct{}
+
+func fromContext(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(ctxKeyLogger{}).(*logrus.Entry); ok {
+		return e
 	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	fromContext(a.ctx).
+		WithField(
+			benchmark.FieldTraceID,
+			benchmark.TraceIDFromContext(a.ctx),
+		).
+		Info(msg)
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	// logrus writes synchronously
+	return 0, nil
+}
+
+// New creates a new logrus based logger adapter
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	l := newLogger(out, conf)
+	hooked := newLogger(out, conf)
+	hooked.AddHook(hostnameHook{})
+
+	fields := benchmark.NewFields10()
+	return &adapter{
+		l: l,
+		with10Exist: l.WithFields(logrus.Fields{
+			fields.Name1:  fields.Value1,
+			fields.Name2:  fields.Value2,
+			fields.Name3:  fields.Value3,
+			fields.Name4:  fields.Value4,
+			fields.Name5:  fields.Value5,
+			fields.Name6:  fields.Value6,
+			fields.Name7:  fields.Value7,
+			fields.Name8:  fields.Value8,
+			fields.Name9:  fields.Value9,
+			fields.Name10: fields.Value10,
+		}),
+		sampler: &benchmark.Sampler{N: benchmark.SampleRate},
+		ctx: context.WithValue(
+			benchmark.NewTraceContext(),
+			ctxKeyLogger{},
+			logrus.NewEntry(l),
+		),
+		hooked: hooked,
+	}, nil
 }
@@ -1,8 +1,9 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -15,15 +16,98 @@ import (
 	"github.com/globusdigital/logbench/benchmark"
 	"github.com/globusdigital/logbench/logrus"
 	"github.com/globusdigital/logbench/phuslog"
+	"github.com/globusdigital/logbench/reference"
+	"github.com/globusdigital/logbench/stdlog"
+	"github.com/globusdigital/logbench/validate"
 	"github.com/globusdigital/logbench/zap"
 	"github.com/globusdigital/logbench/zerolog"
 )
 
-var setups = map[string]benchmark.Setup{
-	"zap":     zap.Setup(),
-	"zerolog": zerolog.Setup(),
-	"logrus":  logrus.Setup(),
-	"phuslog": phuslog.Setup(),
+// referenceLogger is the name of the hand-optimized reference adapter
+// the overhead of the other loggers is reported relative to
+const referenceLogger = "reference"
+
+var adapters = map[string]benchmark.NewAdapter{
+	"zap":     zap.New,
+	"zerolog": zerolog.New,
+	"logrus":  logrus.New,
+	"phuslog": phuslog.New,
+	"stdlog":  stdlog.New,
+
+	// Theoretical floor
+	referenceLogger: reference.New,
+
+	// Asynchronous and buffered variants
+	"zap-buffered":  zap.NewBuffered,
+	"zerolog-diode": zerolog.NewDiode,
+	"phuslog-async": phuslog.NewAsync,
+}
+
+// baseLoggers maps the names of logger variants to the names
+// of the loggers they're based on. Variants only differ in how
+// the output is written and are expected to produce the same output
+var baseLoggers = map[string]string{
+	"zap-buffered":  "zap",
+	"zerolog-diode": "zerolog",
+	"phuslog-async": "phuslog",
+}
+
+// baseLogger returns the name of the logger the given logger is based on
+func baseLogger(loggerName string) string {
+	if base, ok := baseLoggers[loggerName]; ok {
+		return base
+	}
+	return loggerName
+}
+
+// operationsAll lists all operations in the order they're run
+var operationsAll = []string{
+	benchmark.LogOperationInfo,
+	benchmark.LogOperationInfoFmt,
+	benchmark.LogOperationInfoWithErrorStack,
+	benchmark.LogOperationInfoWith3,
+	benchmark.LogOperationInfoWith10,
+	benchmark.LogOperationInfoWith10Exist,
+	benchmark.LogOperationInfoWithN,
+	benchmark.LogOperationInfoLarge1K,
+	benchmark.LogOperationInfoLarge16K,
+	benchmark.LogOperationInfoLarge1M,
+	benchmark.LogOperationInfoEscape,
+	benchmark.LogOperationInfoUnicode,
+	benchmark.LogOperationInfoInvalidUTF8,
+	benchmark.LogOperationInfoChildLogger,
+	benchmark.LogOperationInfoWithContext,
+	benchmark.LogOperationInfoWithTypes,
+	benchmark.LogOperationInfoSampled,
+	benchmark.LogOperationInfoWithHook,
+	benchmark.LogOperationError,
+}
+
+// operationsExplicit lists the operations left out by -o_all
+// since they don't complete in reasonable time with the default target,
+// they only run if enabled by -o
+var operationsExplicit = map[string]bool{
+	// 1 MiB per log writes about 1 TiB with -t 1_000_000
+	benchmark.LogOperationInfoLarge1M: true,
+}
+
+// operationsOfAll returns the operations run with -o_all
+func operationsOfAll() []string {
+	operations := make([]string, 0, len(operationsAll))
+	for _, operation := range operationsAll {
+		if !operationsExplicit[operation] {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// isUnsupported returns true if err indicates that a logger
+// doesn't support the operation or the configured encodings
+func isUnsupported(err error) bool {
+	return errors.Is(err, benchmark.ErrUnsupportedOperation) ||
+		errors.Is(err, benchmark.ErrUnsupportedEncoding) ||
+		errors.Is(err, benchmark.ErrUnsupportedTimeEncoding)
 }
 
 func setupTermSigInterceptor() func() bool {
@@ -51,6 +135,13 @@ func main() {
 	flagOperations := &flagList{name: "operations"}
 	flag.Var(flagOperations, "o", "operations")
 
+	flagFieldTypes := &flagList{name: "field types"}
+	flag.Var(
+		flagFieldTypes,
+		"nt",
+		"field types of the info_with_n operation (all types when empty)",
+	)
+
 	flagTarget := flag.Uint64(
 		"t",
 		1_000_000,
@@ -71,28 +162,115 @@ func main() {
 		"", // Disabled by default
 		"memory profile output file (disabled when empty)",
 	)
+	flagFieldsN := flag.Int(
+		"n",
+		benchmark.DefaultConfig().FieldsN,
+		"number of fields of the info_with_n operation",
+	)
+	flagChildFields := flag.Int(
+		"ck",
+		benchmark.DefaultConfig().ChildFields,
+		"number of fields of the info_child_logger operation's child logger",
+	)
+	flagChildMessages := flag.Int(
+		"cm",
+		benchmark.DefaultConfig().ChildMessages,
+		"number of messages logged through the info_child_logger "+
+			"operation's child logger",
+	)
+	flagEncoding := flag.String(
+		"encoding",
+		string(benchmark.DefaultConfig().Encoding),
+		"output encoding (json|console|logfmt)",
+	)
+	flagTimeEncoding := flag.String(
+		"timefmt",
+		string(benchmark.DefaultConfig().TimeEncoding),
+		"time field encoding (rfc3339nano|rfc3339|unix|unixms|none)",
+	)
+	flagClock := flag.String(
+		"clock",
+		string(benchmark.DefaultConfig().ClockMode),
+		"source of the time of the logs (wall|fixed|fake)",
+	)
+	flagTimeline := flag.String(
+		"timeline",
+		"", // Disabled by default
+		"directory to export the memory timeline of each logger and "+
+			"operation to (disabled when empty)",
+	)
+	flagTimelineFormat := flag.String(
+		"timeline_fmt",
+		timelineCSV,
+		"memory timeline export format (csv|json)",
+	)
 	flagOperationsAll := flag.Bool("o_all", false, "run all operations")
+	flagValidate := flag.String(
+		"validate",
+		"", // Disabled by default
+		"JSON Schema file to validate the records of all loggers against "+
+			"instead of benchmarking (disabled when empty)",
+	)
+	flagIntegrity := flag.Bool(
+		"integrity",
+		false,
+		"check every record written by many concurrent writers "+
+			"for corruption instead of benchmarking",
+	)
+	flagStrict := flag.Bool(
+		"strict",
+		false,
+		"fail if a logger doesn't support one of the operations",
+	)
 
-	flag.Parse()
+	// The verify command compares the output of the loggers
+	// instead of benchmarking them
+	args := os.Args[1:]
+	verifyMode := len(args) > 0 && args[0] == cmdVerify
+	if verifyMode {
+		args = args[1:]
+	}
+	// flag.CommandLine exits on errors
+	_ = flag.CommandLine.Parse(args)
 
 	if *flagOperationsAll {
-		flagOperations.vals = []string{
-			benchmark.LogOperationInfo,
-			benchmark.LogOperationInfoFmt,
-			benchmark.LogOperationInfoWithErrorStack,
-			benchmark.LogOperationInfoWith3,
-			benchmark.LogOperationInfoWith10,
-			benchmark.LogOperationInfoWith10Exist,
-			benchmark.LogOperationError,
+		flagOperations.vals = operationsOfAll()
+	}
+
+	conf := benchmark.DefaultConfig()
+	encoding, err := benchmark.ParseEncoding(*flagEncoding)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf.Encoding = encoding
+	timeEncoding, err := benchmark.ParseTimeEncoding(*flagTimeEncoding)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf.TimeEncoding = timeEncoding
+	clockMode, err := benchmark.ParseClockMode(*flagClock)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf.Clock = benchmark.NewClock(clockMode, time.Now())
+	conf.ClockMode = clockMode
+	conf.FieldsN = *flagFieldsN
+	conf.ChildFields = *flagChildFields
+	conf.ChildMessages = *flagChildMessages
+	for _, name := range flagFieldTypes.vals {
+		tp, err := benchmark.ParseFieldType(name)
+		if err != nil {
+			log.Fatal(err)
 		}
+		conf.FieldTypes = append(conf.FieldTypes, tp)
 	}
 
-	// Prepare
-	stopped := setupTermSigInterceptor()
-	memStatChan := benchmark.StartMemoryWatcher(
-		context.Background(),
-		*flagMemCheckInterval,
-	)
+	// zerolog's time settings are global
+	zerolog.Configure(conf)
+
+	if _, err := timelineExporter(*flagTimelineFormat); err != nil {
+		log.Fatal(err)
+	}
 
 	if len(flagLoggers.vals) < 1 {
 		log.Fatal("no loggers selected")
@@ -104,35 +282,129 @@ func main() {
 
 	flagLoggers.RemoveDuplicates()
 
+	if verifyMode {
+		mismatches, err := verify(flagLoggers.vals, flagOperations.vals, conf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range mismatches {
+			fmt.Println(m)
+		}
+		if len(mismatches) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *flagValidate != "" {
+		schema, err := validate.LoadSchema(*flagValidate)
+		if err != nil {
+			log.Fatal(err)
+		}
+		results, err := validateSchema(
+			flagLoggers.vals,
+			flagOperations.vals,
+			conf,
+			schema,
+			*flagTarget,
+			*flagConcWriters,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printSchemaResults(results)
+		for _, r := range results {
+			if r.Failed() {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	if *flagIntegrity {
+		results, err := checkIntegrity(
+			flagLoggers.vals,
+			flagOperations.vals,
+			conf,
+			*flagTarget,
+			*flagConcWriters,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printIntegrityResults(results)
+		for _, r := range results {
+			if r.Failed() {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// The reference logger is always run
+	// to compare the other loggers against it
+	loggers := flagLoggers.vals
+	implicitReference := true
+	for _, loggerName := range loggers {
+		if loggerName == referenceLogger {
+			implicitReference = false
+		}
+	}
+	if implicitReference {
+		loggers = append(loggers[:len(loggers):len(loggers)], referenceLogger)
+	}
+
+	// Prepare
+	stopped := setupTermSigInterceptor()
+
 	stats := make(
 		map[string]map[string]benchmark.Statistics,
-		len(flagLoggers.vals),
+		len(loggers),
 	)
 
 	start := time.Now()
-	for _, loggerName := range flagLoggers.vals {
-		setupInit, setupExists := setups[loggerName]
-		if !setupExists {
-			log.Fatalf("no setup for logger %q", loggerName)
+	for _, loggerName := range loggers {
+		newAdapter, adapterExists := adapters[loggerName]
+		if !adapterExists {
+			log.Fatalf("no adapter for logger %q", loggerName)
 		}
 
 		for _, operation := range flagOperations.vals {
-			bench, err := benchmark.New(os.Stdout, operation, setupInit)
-			if err != nil {
-				log.Fatalf("setup %q init: %s", loggerName, err)
-			}
-
+			bench, err := benchmark.New(
+				os.Stdout,
+				operation,
+				newAdapter,
+				conf,
+			)
 			if s := stats[loggerName]; s == nil {
 				stats[loggerName] = make(
 					map[string]benchmark.Statistics,
 					len(flagOperations.vals),
 				)
 			}
-			stats[loggerName][operation] = bench.Run(
-				*flagTarget,
-				*flagConcWriters,
-				stopped,
+			switch {
+			case isUnsupported(err) &&
+				(!*flagStrict || implicitReference && loggerName == referenceLogger):
+				// Skip the operation reporting it as unsupported
+				stats[loggerName][operation] = benchmark.Statistics{
+					Unsupported:       true,
+					UnsupportedReason: err.Error(),
+				}
+				continue
+			case err != nil:
+				log.Fatalf("adapter %q init: %s", loggerName, err)
+			}
+
+			watcher := benchmark.StartMemoryWatcher(
+				*flagMemCheckInterval,
+				*flagTimeline != "",
 			)
+			s := bench.Run(*flagTarget, *flagConcWriters, stopped)
+			s.Memory = watcher.Stop()
+			if s.FlushErr != nil {
+				log.Fatalf("flushing %q: %s", loggerName, s.FlushErr)
+			}
+			stats[loggerName][operation] = s
 		}
 	}
 	timeTotal := time.Since(start)
@@ -141,12 +413,21 @@ func main() {
 		timeTotal,
 		*flagTarget,
 		*flagConcWriters,
-		memStatChan,
-		flagLoggers.vals,
+		conf,
+		*flagMemCheckInterval,
+		loggers,
 		flagOperations.vals,
 		stats,
 	)
 
+	// Export memory timelines
+	if *flagTimeline != "" {
+		err := writeTimelines(*flagTimeline, *flagTimelineFormat, stats)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Write memory profile
 	if *flagMemoryProfile != "" {
 		runtime.GC()
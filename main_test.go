@@ -1,347 +1,522 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"reflect"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+	"github.com/globusdigital/logbench/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
-// SyncBuffer is a thread-safe buffer implementing the io.ReadWriter interface
-type SyncBuffer struct {
-	m sync.Mutex
-	b bytes.Buffer
+func TestOperationsOfAll(t *testing.T) {
+	operations := operationsOfAll()
+	require.NotContains(t, operations, benchmark.LogOperationInfoLarge1M)
+	require.Len(t, operations, len(operationsAll)-len(operationsExplicit))
 }
 
-func (b *SyncBuffer) Read(p []byte) (n int, err error) {
-	b.m.Lock()
-	defer b.m.Unlock()
-	return b.b.Read(p)
+// invalidJSON maps logger names to the operations the logger is known
+// to produce invalid JSON for and the according reason
+var invalidJSON = map[string]map[string]string{
+	"phuslog": {
+		benchmark.LogOperationInfoEscape: "control characters other than " +
+			"\\b, \\f, \\n, \\r, \\t and \\x00 are written unescaped",
+	},
 }
 
-func (b *SyncBuffer) Write(p []byte) (n int, err error) {
-	b.m.Lock()
-	defer b.m.Unlock()
-	return b.b.Write(p)
+// validatorsOf returns the validators of the given logger and operation
+func validatorsOf(
+	t *testing.T,
+	fieldValidators map[string]validate.FV,
+	loggerValidators map[string]map[string]validate.FV,
+	loggerName string,
+	operationName string,
+) validate.FV {
+	validators, err := operationValidators(
+		fieldValidators,
+		loggerValidators,
+		loggerName,
+		operationName,
+	)
+	require.NoError(t, err)
+	return validators
 }
 
-func (b *SyncBuffer) String() string {
-	b.m.Lock()
-	defer b.m.Unlock()
-	return b.b.String()
+// recordDecoder decodes the records written by a logger
+type recordDecoder interface {
+	More() bool
+	Decode(v interface{}) error
 }
 
-// FV represents a mapping between field names
-// and the according list validators
-type FV map[string]func(interface{}) error
+// decoders maps the names of loggers not writing JSON to the decoders
+// of their records. Decoders must produce the same types encoding/json
+// produces when decoding into an interface{}
+var decoders = map[string]func(io.Reader) recordDecoder{}
 
-func expectString(actual interface{}) (string, error) {
-	val, ok := actual.(string)
-	if !ok {
-		return "", fmt.Errorf(
-			"unexpected field type (expected: string; got: %s)",
-			reflect.TypeOf(actual),
-		)
+// newDecoder returns the record decoder of the given logger
+func newDecoder(loggerName string, r io.Reader) recordDecoder {
+	if newDec, ok := decoders[loggerName]; ok {
+		return newDec(r)
 	}
-	return val, nil
+	return json.NewDecoder(r)
 }
 
-func expectBool(actual interface{}) (bool, error) {
-	val, ok := actual.(bool)
-	if !ok {
-		return false, fmt.Errorf(
-			"unexpected field type (expected: bool; got: %s)",
-			reflect.TypeOf(actual),
-		)
-	}
-	return val, nil
-}
+func TestFormat(t *testing.T) {
+	conf := benchmark.DefaultConfig()
+	fieldValidators, loggerValidators := newFieldValidators(conf)
 
-func expectFloat64(actual interface{}) (float64, error) {
-	val, ok := actual.(float64)
-	if !ok {
-		return 0, fmt.Errorf(
-			"unexpected field type (expected: int; got: %s)",
-			reflect.TypeOf(actual),
-		)
-	}
-	return val, nil
-}
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			for operationName := range fieldValidators {
+				t.Run(operationName, func(t *testing.T) {
+					buf := new(SyncBuffer)
+					bench, err := benchmark.New(
+						buf,
+						operationName,
+						newAdapter,
+						conf,
+					)
+					if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+						t.Skipf("logger %q doesn't support the operation", loggerName)
+					}
+					require.NoError(t, err)
+					stats := bench.Run(1, 1, nil)
+					require.Equal(t, uint64(1), stats.TotalLogsWritten)
+					require.NoError(t, stats.FlushErr)
+					require.Equal(
+						t,
+						uint64(buf.Len()),
+						stats.TotalBytesWritten,
+					)
 
-func validateTime(actual interface{}) error {
-	val, err := expectString(actual)
-	if err != nil {
-		return err
-	}
+					var fields map[string]interface{}
+					dec := newDecoder(loggerName, buf)
 
-	_, err = time.Parse(time.RFC3339, val)
-	return err
-}
+					invalid := invalidJSON[baseLogger(loggerName)]
+					if reason, ok := invalid[operationName]; ok {
+						// Make sure the deviation is still present
+						require.Error(
+							t,
+							dec.Decode(&fields),
+							"logger %q was expected to produce invalid JSON: %s",
+							loggerName,
+							reason,
+						)
+						return
+					}
 
-func newValidatorLevel(expected string) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, err := expectString(actual)
-		if err != nil {
-			return err
-		}
-		if val != expected {
-			return fmt.Errorf(
-				"mismatching level: (expected: %q, got: %q)",
-				expected,
-				val,
-			)
-		}
-		return nil
-	}
-}
+					require.NoError(
+						t,
+						dec.Decode(&fields),
+						"decoding output of logger %q",
+						loggerName,
+					)
 
-func newValidatorText(expected string) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, err := expectString(actual)
-		if err != nil {
-			return err
-		}
-		if val != expected {
-			return fmt.Errorf(
-				"mismatching text: (expected: %q, got: %q)",
-				expected,
-				val,
-			)
-		}
-		return nil
+					validators := validatorsOf(
+						t,
+						fieldValidators,
+						loggerValidators,
+						loggerName,
+						operationName,
+					)
+					require.NoError(
+						t,
+						validators.Validate(fields),
+						"invalid record of logger %q",
+						loggerName,
+					)
+				})
+			}
+		})
 	}
 }
 
-func newValidatorBool(expected bool) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, err := expectBool(actual)
-		if err != nil {
-			return err
-		}
-		if val != expected {
-			return fmt.Errorf("mismatching bool: (expected: %t)", expected)
-		}
-		return nil
-	}
-}
+func TestChildLoggerMessages(t *testing.T) {
+	conf := benchmark.DefaultConfig()
+	conf.ChildFields = 2
+	conf.ChildMessages = 4
 
-func newValidatorInt(expected int) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, err := expectFloat64(actual)
-		if err != nil {
-			return err
-		}
-		if val != float64(expected) {
-			return fmt.Errorf(
-				"mismatching int: (expected: %d, got: %f)",
-				expected,
-				val,
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			buf := new(SyncBuffer)
+			bench, err := benchmark.New(
+				buf,
+				benchmark.LogOperationInfoChildLogger,
+				newAdapter,
+				conf,
 			)
-		}
-		return nil
-	}
-}
+			if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+				t.Skipf("logger %q doesn't support the operation", loggerName)
+			}
+			require.NoError(t, err)
+			stats := bench.Run(3, 1, nil)
+			require.Equal(t, uint64(3), stats.TotalLogsWritten)
 
-func newValidatorFloat64(expected float64) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, err := expectFloat64(actual)
-		if err != nil {
-			return err
-		}
-		if val != expected {
-			return fmt.Errorf(
-				"mismatching float64: (expected: %f, got: %f)",
-				expected,
-				val,
-			)
-		}
-		return nil
+			records := 0
+			dec := newDecoder(loggerName, buf)
+			for dec.More() {
+				var fields map[string]interface{}
+				require.NoError(t, dec.Decode(&fields))
+				for _, f := range benchmark.NewFieldsN(
+					conf.ChildFields,
+					[]benchmark.FieldType{benchmark.FieldTypeString},
+				) {
+					require.Contains(t, fields, f.Name)
+				}
+				records++
+			}
+			require.Equal(t, 3*conf.ChildMessages, records)
+		})
 	}
 }
 
-func newValidatorStrings(expected []string) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, ok := actual.([]interface{})
-		if !ok {
-			return fmt.Errorf(
-				"unexpected field type (expected: []string; got: %s)",
-				reflect.TypeOf(actual),
-			)
-		}
-		for i, val := range val {
-			val, err := expectString(val)
-			if err != nil {
-				return err
+func TestInfoWithNConcurrent(t *testing.T) {
+	const writers = 8
+	const logs = 200
+
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			buf := new(SyncBuffer)
+			a, err := newAdapter(buf, benchmark.DefaultConfig())
+			require.NoError(t, err)
+			l, ok := a.(benchmark.FieldsLogger)
+			if !ok {
+				t.Skipf("logger %q doesn't support info_with_n", loggerName)
 			}
-			expected := expected[i]
-			if expected != val {
-				return fmt.Errorf(
-					"mismatching array item: (expected: %s, got: %s)",
-					expected,
-					val,
+
+			// Every writer logs its own number as both message and field,
+			// records mixing the fields of concurrent calls don't match
+			var wg sync.WaitGroup
+			wg.Add(writers)
+			for w := 0; w < writers; w++ {
+				msg := strconv.Itoa(w)
+				fields := benchmark.FieldsN{{
+					Name: "writer",
+					Type: benchmark.FieldTypeInt,
+					Int:  w,
+				}}
+				go func() {
+					defer wg.Done()
+					for i := 0; i < logs; i++ {
+						l.InfoWithN(msg, fields)
+					}
+				}()
+			}
+			wg.Wait()
+			dropped, err := a.Flush()
+			require.NoError(t, err)
+
+			records := 0
+			dec := newDecoder(loggerName, buf)
+			for dec.More() {
+				var fields map[string]interface{}
+				require.NoError(t, dec.Decode(&fields))
+				require.Contains(t, fields, "writer")
+				require.Equal(
+					t,
+					fields[benchmark.FieldMessage],
+					fmt.Sprint(fields["writer"]),
 				)
+				records++
 			}
-		}
-		return nil
+			// The diode writer drops logs when its buffer is full
+			require.Equal(t, writers*logs-int(dropped), records)
+		})
 	}
 }
 
-func newValidatorInts(expected []int) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, ok := actual.([]interface{})
-		if !ok {
-			return fmt.Errorf(
-				"unexpected field type (expected: []int; got: %s)",
-				reflect.TypeOf(actual),
+func TestSampling(t *testing.T) {
+	const target = 10*benchmark.SampleRate + 3
+	const expected = (target + benchmark.SampleRate - 1) / benchmark.SampleRate
+	conf := benchmark.DefaultConfig()
+
+	for loggerName, newAdapter := range adapters {
+		t.Run(loggerName, func(t *testing.T) {
+			buf := new(SyncBuffer)
+			bench, err := benchmark.New(
+				buf,
+				benchmark.LogOperationInfoSampled,
+				newAdapter,
+				conf,
 			)
-		}
-		for i, val := range val {
-			val, err := expectFloat64(val)
-			if err != nil {
-				return err
+			if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+				t.Skipf("logger %q doesn't support the operation", loggerName)
 			}
-			expected := expected[i]
+			require.NoError(t, err)
+			stats := bench.Run(target, 1, nil)
+			require.Equal(t, uint64(target), stats.TotalLogsWritten)
+			require.NoError(t, stats.FlushErr)
+			require.Zero(t, stats.TotalLogsDropped)
+			require.Equal(t, uint64(expected), stats.TotalLogsEmitted)
 
-			if float64(expected) != val {
-				return fmt.Errorf(
-					"mismatching array item: (expected: %d, got: %f)",
-					expected,
-					val,
-				)
+			records := 0
+			dec := newDecoder(loggerName, buf)
+			for dec.More() {
+				var fields map[string]interface{}
+				require.NoError(t, dec.Decode(&fields))
+				records++
 			}
-		}
-		return nil
+			require.Equal(t, expected, records)
+		})
 	}
 }
 
-func newValidatorFloat64s(expected []float64) func(interface{}) error {
-	return func(actual interface{}) error {
-		val, ok := actual.([]interface{})
-		if !ok {
-			return fmt.Errorf(
-				"unexpected field type (expected: []interface{}; got: %s)",
-				reflect.TypeOf(actual),
-			)
-		}
-		for i, val := range val {
-			val, err := expectFloat64(val)
-			if err != nil {
-				return err
-			}
-			expected := expected[i]
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
 
-			if float64(expected) != val {
-				return fmt.Errorf(
-					"mismatching array item: (expected: %f, got: %f)",
-					expected,
-					val,
-				)
-			}
+// consoleMessage returns the leading part of msg up to the first character
+// console encoders might escape or quote
+func consoleMessage(msg string) string {
+	for i, r := range msg {
+		if r == '"' || r == '\\' || r == utf8.RuneError || !strconv.IsPrint(r) {
+			return msg[:i]
 		}
-		return nil
 	}
+	return msg
 }
 
-func TestFormat(t *testing.T) {
-	fields3 := benchmark.NewFields3()
-	fields10 := benchmark.NewFields10()
-
-	fieldValidators := map[string]FV{
-		benchmark.LogOperationInfo: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information"),
-		},
-		benchmark.LogOperationInfoFmt: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information 42"),
-		},
-		benchmark.LogOperationInfoWithErrorStack: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information"),
-			benchmark.FieldError:   newValidatorText("error with stack trace"),
-		},
-		benchmark.LogOperationError: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelError),
-			benchmark.FieldMessage: newValidatorText("error message"),
-		},
-		benchmark.LogOperationInfoWith3: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information"),
-			fields3.Name1:          newValidatorText(fields3.Value1),
-			fields3.Name2:          newValidatorInt(fields3.Value2),
-			fields3.Name3:          newValidatorFloat64(fields3.Value3),
-		},
-		benchmark.LogOperationInfoWith10: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information"),
-			fields10.Name1:         newValidatorText(fields10.Value1),
-			fields10.Name2:         newValidatorText(fields10.Value2),
-			fields10.Name3:         newValidatorText(fields10.Value3),
-			fields10.Name4:         newValidatorBool(fields10.Value4),
-			fields10.Name5:         newValidatorText(fields10.Value5),
-			fields10.Name6:         newValidatorInt(fields10.Value6),
-			fields10.Name7:         newValidatorFloat64(fields10.Value7),
-			fields10.Name8:         newValidatorStrings(fields10.Value8),
-			fields10.Name9:         newValidatorInts(fields10.Value9),
-			fields10.Name10:        newValidatorFloat64s(fields10.Value10),
-		},
-		benchmark.LogOperationInfoWith10Exist: {
-			benchmark.FieldTime:    validateTime,
-			benchmark.FieldLevel:   newValidatorLevel(benchmark.LevelInfo),
-			benchmark.FieldMessage: newValidatorText("information"),
-			fields10.Name1:         newValidatorText(fields10.Value1),
-			fields10.Name2:         newValidatorText(fields10.Value2),
-			fields10.Name3:         newValidatorText(fields10.Value3),
-			fields10.Name4:         newValidatorBool(fields10.Value4),
-			fields10.Name5:         newValidatorText(fields10.Value5),
-			fields10.Name6:         newValidatorInt(fields10.Value6),
-			fields10.Name7:         newValidatorFloat64(fields10.Value7),
-			fields10.Name8:         newValidatorStrings(fields10.Value8),
-			fields10.Name9:         newValidatorInts(fields10.Value9),
-			fields10.Name10:        newValidatorFloat64s(fields10.Value10),
-		},
+// consoleExpectation returns the level and the message
+// expected in the console output of the given operation
+func consoleExpectation(operation string) (level, msg string) {
+	switch operation {
+	case benchmark.LogOperationError:
+		return benchmark.LevelError, "error message"
+	case benchmark.LogOperationInfoFmt:
+		return benchmark.LevelInfo, "information 42"
 	}
+	if msg := benchmark.PayloadMessage(operation); msg != "" {
+		return benchmark.LevelInfo, consoleMessage(msg)
+	}
+	return benchmark.LevelInfo, "information"
+}
 
-	for loggerName, initFn := range setups {
+func TestConsoleFormat(t *testing.T) {
+	conf := benchmark.DefaultConfig()
+	conf.Encoding = benchmark.EncodingConsole
+
+	for loggerName, newAdapter := range adapters {
 		t.Run(loggerName, func(t *testing.T) {
-			for operationName, validators := range fieldValidators {
+			for _, operationName := range operationsAll {
 				t.Run(operationName, func(t *testing.T) {
 					buf := new(SyncBuffer)
-					bench, err := benchmark.New(buf, operationName, initFn)
+					bench, err := benchmark.New(
+						buf,
+						operationName,
+						newAdapter,
+						conf,
+					)
+					if errors.Is(err, benchmark.ErrUnsupportedEncoding) {
+						t.Skipf("logger %q doesn't support console", loggerName)
+					}
+					if errors.Is(err, benchmark.ErrUnsupportedOperation) {
+						t.Skipf("logger %q doesn't support the operation", loggerName)
+					}
 					require.NoError(t, err)
 					stats := bench.Run(1, 1, nil)
 					require.Equal(t, uint64(1), stats.TotalLogsWritten)
 
-					var fields map[string]interface{}
-					dec := json.NewDecoder(buf)
-					require.NoError(
+					level, msg := consoleExpectation(operationName)
+					out := buf.String()
+
+					// Console encoders either print the full level name
+					// or its 3-letter abbreviation
+					require.Regexp(
 						t,
-						dec.Decode(&fields),
-						"decoding JSON for logger %q",
+						regexp.MustCompile(
+							`(?i)\b(`+level+`|`+level[:3]+`)\b`,
+						),
+						out,
+						"missing level in output of logger %q",
 						loggerName,
 					)
+					require.True(
+						t,
+						strings.Contains(out, msg),
+						"missing message %q in output of logger %q",
+						truncate(msg, 64),
+						loggerName,
+					)
+				})
+			}
+		})
+	}
+}
 
-					for requiredField, validate := range validators {
-						require.Contains(t, fields, requiredField)
-						require.NoError(
-							t,
-							validate(fields[requiredField]),
-							"invalid value for field %q of logger %q",
-							requiredField,
-							loggerName,
+func TestSchemaValidation(t *testing.T) {
+	schema, err := validate.LoadSchema("validate/testdata/record.schema.json")
+	require.NoError(t, err)
+
+	// Only loggers writing JSON can be validated
+	var loggers []string
+	for loggerName := range adapters {
+		if _, ok := decoders[loggerName]; !ok {
+			loggers = append(loggers, loggerName)
+		}
+	}
+
+	results, err := validateSchema(
+		loggers,
+		operationsAll,
+		benchmark.DefaultConfig(),
+		schema,
+		3,
+		1,
+	)
+	require.NoError(t, err)
+	for _, r := range results {
+		if _, ok := invalidJSON[baseLogger(r.Logger)][r.Operation]; ok {
+			require.Error(t, r.Err, "%s: %s", r.Logger, r.Operation)
+			continue
+		}
+		require.NoError(t, r.Err, "%s: %s", r.Logger, r.Operation)
+		require.NoError(
+			t,
+			r.Report.FirstErr,
+			"%s: %s",
+			r.Logger,
+			r.Operation,
+		)
+		require.NotZero(t, r.Report.Records, "%s: %s", r.Logger, r.Operation)
+	}
+}
+
+// synchronizedLoggers maps the names of loggers never writing
+// to their output concurrently to the according reason
+var synchronizedLoggers = map[string]string{
+	"logrus":        "Logger locks its mutex while writing",
+	"stdlog":        "log.Logger locks its mutex while writing",
+	"zap-buffered":  "BufferedWriteSyncer locks its mutex while writing",
+	"zerolog-diode": "a single goroutine writes",
+}
+
+func TestIntegrity(t *testing.T) {
+	// Only loggers writing JSON can be checked
+	var loggers []string
+	for loggerName := range adapters {
+		if _, notJSON := decoders[loggerName]; !notJSON {
+			loggers = append(loggers, loggerName)
+		}
+	}
+
+	results, err := checkIntegrity(
+		loggers,
+		operationsAll,
+		benchmark.DefaultConfig(),
+		200,
+		1,
+	)
+	require.NoError(t, err)
+	for _, r := range results {
+		if _, ok := invalidJSON[baseLogger(r.Logger)][r.Operation]; ok {
+			require.True(t, r.Failed(), "%s: %s", r.Logger, r.Operation)
+			continue
+		}
+		require.False(
+			t,
+			r.Failed(),
+			"%s: %s: %v",
+			r.Logger,
+			r.Operation,
+			r.Report.FirstErr,
+		)
+		require.NotZero(t, r.Report.Lines, "%s: %s", r.Logger, r.Operation)
+		if _, ok := synchronizedLoggers[r.Logger]; ok {
+			require.Zero(t, r.Overlaps, "%s: %s", r.Logger, r.Operation)
+		}
+	}
+}
+
+// configureZerolog sets zerolog's global time settings for the test
+// restoring the defaults when it completes
+func configureZerolog(t *testing.T, conf benchmark.Config) {
+	zerolog.Configure(conf)
+	t.Cleanup(func() { zerolog.Configure(benchmark.DefaultConfig()) })
+}
+
+func TestTimeEncodings(t *testing.T) {
+	clockTime := time.Date(2021, time.February, 3, 4, 5, 6, 789000000, time.UTC)
+
+	for _, encoding := range benchmark.TimeEncodingsAll() {
+		t.Run(string(encoding), func(t *testing.T) {
+			for loggerName, newAdapter := range adapters {
+				if _, ok := decoders[loggerName]; ok {
+					// Only loggers writing JSON can be checked
+					continue
+				}
+				t.Run(loggerName, func(t *testing.T) {
+					for _, clock := range []func() time.Time{
+						nil,
+						benchmark.FixedClock(clockTime),
+					} {
+						conf := benchmark.DefaultConfig()
+						conf.TimeEncoding = encoding
+						conf.Clock = clock
+						configureZerolog(t, conf)
+
+						buf := new(SyncBuffer)
+						start := time.Now()
+						bench, err := benchmark.New(
+							buf,
+							benchmark.LogOperationInfo,
+							newAdapter,
+							conf,
 						)
+						if errors.Is(err, benchmark.ErrUnsupportedTimeEncoding) {
+							t.Skipf("logger %q doesn't support the time encoding", loggerName)
+						}
+						require.NoError(t, err)
+						require.NoError(t, bench.Run(1, 1, nil).FlushErr)
+
+						var record map[string]json.RawMessage
+						require.NoError(t, json.NewDecoder(buf).Decode(&record))
+						actual, ok := record[benchmark.FieldTime]
+						if encoding == benchmark.TimeNone {
+							require.False(t, ok, "unexpected time field: %s", actual)
+							continue
+						}
+						require.True(t, ok, "missing time field")
+
+						if clock != nil {
+							require.Equal(
+								t,
+								string(benchmark.AppendTime(nil, clockTime, encoding)),
+								string(actual),
+							)
+							continue
+						}
+
+						// The wall clock was read during the benchmark
+						var tm time.Time
+						switch encoding {
+						case benchmark.TimeUnix:
+							sec, err := strconv.ParseInt(string(actual), 10, 64)
+							require.NoError(t, err)
+							tm = time.Unix(sec, 0)
+						case benchmark.TimeUnixMs:
+							ms, err := strconv.ParseInt(string(actual), 10, 64)
+							require.NoError(t, err)
+							tm = time.UnixMilli(ms)
+						default:
+							var s string
+							require.NoError(t, json.Unmarshal(actual, &s))
+							tm, err = time.Parse(encoding.Layout(), s)
+							require.NoError(t, err)
+						}
+						require.WithinDuration(t, start, tm, time.Minute)
 					}
 				})
 			}
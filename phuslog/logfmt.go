@@ -0,0 +1,55 @@
+package phuslog
+
+import (
+	"io"
+
+	"github.com/globusdigital/logbench/benchmark"
+	phuslog "github.com/phuslu/log"
+)
+
+// logfmtFormatter is a phuslog.ConsoleWriter formatter writing logfmt lines.
+// phuslog's own LogfmtFormatter writes the message without a key
+func logfmtFormatter(out io.Writer, args *phuslog.FormatterArgs) (int, error) {
+	buf := bufPool.Get().(*[]byte)
+	b := (*buf)[:0]
+
+	b = append(b, benchmark.FieldTime...)
+	b = append(b, '=')
+	b = benchmark.AppendLogfmtValue(b, args.Time)
+	b = append(b, ' ')
+	b = append(b, benchmark.FieldLevel...)
+	b = append(b, '=')
+	b = benchmark.AppendLogfmtValue(b, args.Level)
+	if args.Caller != "" {
+		b = append(b, " caller="...)
+		b = benchmark.AppendLogfmtValue(b, args.Caller)
+	}
+	if args.Stack != "" {
+		b = append(b, " stack="...)
+		b = benchmark.AppendLogfmtValue(b, args.Stack)
+	}
+	b = append(b, ' ')
+	b = append(b, benchmark.FieldMessage...)
+	b = append(b, '=')
+	b = benchmark.AppendLogfmtValue(b, args.Message)
+
+	for _, kv := range args.KeyValues {
+		b = append(b, ' ')
+		b = append(b, kv.Key...)
+		b = append(b, '=')
+		switch kv.ValueType {
+		case 's', 'o':
+			// Strings as well as arrays and objects in their JSON form
+			b = benchmark.AppendLogfmtValue(b, kv.Value)
+		default:
+			// Numbers, booleans and null
+			b = append(b, kv.Value...)
+		}
+	}
+	b = append(b, '\n')
+
+	n, err := out.Write(b)
+	*buf = b
+	bufPool.Put(buf)
+	return n, err
+}
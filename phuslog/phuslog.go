@@ -1,92 +1,373 @@
 package phuslog
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/globusdigital/logbench/benchmark"
 	phuslog "github.com/phuslu/log"
 )
 
-func init() {
+// adapter is the phuslog logger adapter
+type adapter struct {
+	l phuslog.Logger
+
+	// with10Exist and hooked are derived from l
+	with10Exist phuslog.Logger
+	hooked      phuslog.Logger
+	sampler     *benchmark.Sampler
+	ctx         context.Context
+
+	flush benchmark.FnFlush
 }
 
-func newLogger(out io.ReadWriter) phuslog.Logger {
-	// Initialize logger
-	return phuslog.Logger{
-		Level:  phuslog.InfoLevel,
-		Writer: &phuslog.IOWriter{out},
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+)
+
+// bufPool pools the buffers the fields and records
+// of a single log are encoded into
+var bufPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 512)
+	return &b
+}}
+
+// newContext takes a buffer from the pool
+// and begins a context encoded into it
+func newContext() (*phuslog.Entry, *[]byte) {
+	buf := bufPool.Get().(*[]byte)
+	return phuslog.NewContext((*buf)[:0]), buf
+}
+
+// infoWith writes an info log with the given context
+// and returns the buffer of the context to the pool
+func (a *adapter) infoWith(msg string, ctx phuslog.Context, buf *[]byte) {
+	l := a.l
+	l.Context = ctx
+	l.Info().Msg(msg)
+	*buf = ctx
+	bufPool.Put(buf)
+}
+
+// asyncSize is the number of logs buffered by the asynchronous writer
+const asyncSize = 1000
+
+// asyncWriter is an asynchronous writer handing the records off
+// to a goroutine writing them to the output. It copies each record
+// before handing it off unlike phuslog.AsyncWriter, which reads
+// the entries afterwards and is thus racy under concurrent writers.
+// It blocks when its buffer is full and never drops records
+type asyncWriter struct {
+	out     io.Writer
+	records chan *[]byte
+	done    chan struct{}
+
+	// err is the first error of the output
+	err error
+}
+
+// newAsyncWriter creates an asynchronous writer
+// and the function flushing and closing it
+func newAsyncWriter(out io.Writer) (io.Writer, benchmark.FnFlush) {
+	w := &asyncWriter{
+		out:     out,
+		records: make(chan *[]byte, asyncSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, func() (uint64, error) {
+		close(w.records)
+		<-w.done
+		return 0, w.err
 	}
 }
 
-func newInfo(out io.ReadWriter) (benchmark.FnInfo, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Info().Msg(msg)
-	}, nil
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for buf := range w.records {
+		if _, err := w.out.Write(*buf); err != nil && w.err == nil {
+			w.err = err
+		}
+		bufPool.Put(buf)
+	}
 }
 
-func newInfoFmt(out io.ReadWriter) (benchmark.FnInfoFmt, error) {
-	l := newLogger(out)
-	return func(msg string, data int) {
-		l.Info().Msgf(msg, data)
-	}, nil
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	buf := bufPool.Get().(*[]byte)
+	*buf = append((*buf)[:0], p...)
+	w.records <- buf
+	return len(p), nil
 }
 
-func newInfoWithErrorStack(out io.ReadWriter) (
-	benchmark.FnInfoWithErrorStack, error,
-) {
-	l := newLogger(out)
-	return func(msg string, err error) {
-		l.Context = phuslog.NewContext(l.Context[:0]).Err(err).Value()
-		l.Info().Msg(msg)
-	}, nil
-}
-
-func newError(out io.ReadWriter) (benchmark.FnError, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Error().Msg(msg)
-	}, nil
-}
-
-func newInfoWith3(out io.ReadWriter) (benchmark.FnInfoWith3, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields3) {
-		l.Context = phuslog.NewContext(l.Context[:0]).
-			Str(fields.Name1, fields.Value1).
-			Int(fields.Name2, fields.Value2).
-			Float64(fields.Name3, fields.Value3).
-			Value()
-		l.Info().Msg(msg)
-	}, nil
-}
-
-func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields10) {
-		l.Context = phuslog.NewContext(l.Context[:0]).
-			Str(fields.Name1, fields.Value1).
-			Str(fields.Name2, fields.Value2).
-			Str(fields.Name3, fields.Value3).
-			Bool(fields.Name4, fields.Value4).
-			Str(fields.Name5, fields.Value5).
-			Int(fields.Name6, fields.Value6).
-			Float64(fields.Name7, fields.Value7).
-			Strs(fields.Name8, fields.Value8).
-			Ints(fields.Name9, fields.Value9).
-			Floats64(fields.Name10, fields.Value10).
-			Value()
-		l.Info().Msg(msg)
-	}, nil
-}
-
-func newInfoWith10Exist(out io.ReadWriter) (
-	benchmark.FnInfoWith10Exist,
-	error,
+func newWriter(out io.Writer, conf benchmark.Config) phuslog.Writer {
+	switch conf.Encoding {
+	case benchmark.EncodingConsole:
+		return &phuslog.ConsoleWriter{Writer: out}
+	case benchmark.EncodingLogfmt:
+		return &phuslog.ConsoleWriter{
+			Writer:    out,
+			Formatter: logfmtFormatter,
+		}
+	}
+	return &phuslog.IOWriter{Writer: out}
+}
+
+func withFields(c *phuslog.Entry, fields benchmark.FieldsN) *phuslog.Entry {
+	for _, f := range fields {
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			c = c.Str(f.Name, f.String)
+		case benchmark.FieldTypeInt:
+			c = c.Int(f.Name, f.Int)
+		case benchmark.FieldTypeFloat64:
+			c = c.Float64(f.Name, f.Float64)
+		case benchmark.FieldTypeBool:
+			c = c.Bool(f.Name, f.Bool)
+		case benchmark.FieldTypeDuration:
+			c = c.Dur(f.Name, f.Duration)
+		case benchmark.FieldTypeTime:
+			c = c.Time(f.Name, f.Time)
+		}
+	}
+	return c
+}
+
+func (a *adapter) Info(msg string) {
+	a.l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoFmt(msg string, data int) {
+	a.l.Info().Msgf(msg, data)
+}
+
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	c, buf := newContext()
+	ctx := c.
+		Err(err).
+		Value()
+	a.infoWith(msg, ctx, buf)
+}
+
+func (a *adapter) Error(msg string) {
+	a.l.Error().Msg(msg)
+}
+
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	c, buf := newContext()
+	ctx := c.
+		Str(fields.Name1, fields.Value1).
+		Int(fields.Name2, fields.Value2).
+		Float64(fields.Name3, fields.Value3).
+		Value()
+	a.infoWith(msg, ctx, buf)
+}
+
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	c, buf := newContext()
+	ctx := c.
+		Str(fields.Name1, fields.Value1).
+		Str(fields.Name2, fields.Value2).
+		Str(fields.Name3, fields.Value3).
+		Bool(fields.Name4, fields.Value4).
+		Str(fields.Name5, fields.Value5).
+		Int(fields.Name6, fields.Value6).
+		Float64(fields.Name7, fields.Value7).
+		Strs(fields.Name8, fields.Value8).
+		Ints(fields.Name9, fields.Value9).
+		Floats64(fields.Name10, fields.Value10).
+		Value()
+	a.infoWith(msg, ctx, buf)
+}
+
+func (a *adapter) InfoWith10Exist(msg string) {
+	a.with10Exist.Info().Msg(msg)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	c, buf := newContext()
+	ctx := withFields(c, fields).Value()
+	a.infoWith(msg, ctx, buf)
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
 ) {
+	c := a.l
+	c.Context = withFields(phuslog.NewContext(nil), fields).Value()
+	for i := 0; i < messages; i++ {
+		c.Info().Msg(msg)
+	}
+}
+
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	c, buf := newContext()
+	ctx := c.
+		Time(fields.Name1, fields.Value1).
+		Dur(fields.Name2, fields.Value2).
+		Bytes(fields.Name3, fields.Value3).
+		Hex(fields.Name4, fields.Value4).
+		IPAddr(fields.Name5, fields.Value5).
+		Errs(fields.Name6, fields.Value6).
+		Value()
+	a.infoWith(msg, ctx, buf)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	// phuslog doesn't provide a built-in sampler
+	if a.sampler.Sample() {
+		a.l.Info().Msg(msg)
+	}
+}
+
+// hostnameWriter is a writer wrapper adding the hostname field
+// since phuslog doesn't provide hooks
+type hostnameWriter struct {
+	next phuslog.Writer
+}
+
+var hostnameField = []byte(
+	`,"` + benchmark.FieldHostname + `":"` + benchmark.Hostname + `"}` + "\n",
+)
+
+func (w *hostnameWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	// Replace the closing "}\n" of the entry by the hostname field
+	b := e.Value()
+	buf := bufPool.Get().(*[]byte)
+	*buf = append(append((*buf)[:0], b[:len(b)-2]...), hostnameField...)
+	hooked := phuslog.NewContext(*buf)
+	hooked.Level = e.Level
+	n, err := w.next.WriteEntry(hooked)
+	bufPool.Put(buf)
+	return n, err
+}
+
+// clockWriter is a writer wrapper replacing the time of the entries
+// by the time provided by the clock of the benchmark configuration
+// since phuslog has no clock hook. phuslog still reads the wall clock
+// when writing the header, its time is only replaced
+type clockWriter struct {
+	next     phuslog.Writer
+	now      func() time.Time
+	encoding benchmark.TimeEncoding
+}
+
+// timePrefix precedes the time value of every entry
+const timePrefix = `{"time":`
+
+func (w *clockWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	// The time value is followed by the level field
+	b := e.Value()
+	end := len(timePrefix) + bytes.IndexByte(b[len(timePrefix):], ',')
+	var tmp [64]byte
+	t := benchmark.AppendTime(tmp[:0], w.now(), w.encoding)
+	if len(t) == end-len(timePrefix) {
+		// Replace the time in place if it's of the same width
+		copy(b[len(timePrefix):], t)
+		return w.next.WriteEntry(e)
+	}
+
+	buf := bufPool.Get().(*[]byte)
+	*buf = append(append(append((*buf)[:0], timePrefix...), t...), b[end:]...)
+	stamped := phuslog.NewContext(*buf)
+	stamped.Level = e.Level
+	n, err := w.next.WriteEntry(stamped)
+	bufPool.Put(buf)
+	return n, err
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	a.hooked.Info().Msg(msg)
+}
+
+type ctxKeyLogger struct{}
+
+func fromContext(ctx context.Context) *phuslog.Logger {
+	if l, ok := ctx.Value(ctxKeyLogger{}).(*phuslog.Logger); ok {
+		return l
+	}
+	return &phuslog.DefaultLogger
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	fromContext(a.ctx).Info().
+		Str(benchmark.FieldTraceID, benchmark.TraceIDFromContext(a.ctx)).
+		Msg(msg)
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	if a.flush == nil {
+		// phuslog writes synchronously
+		return 0, nil
+	}
+	return a.flush()
+}
+
+// timeFormat returns the phuslog time format of the given time encoding
+func timeFormat(e benchmark.TimeEncoding) (string, error) {
+	switch e {
+	case benchmark.TimeUnix:
+		return phuslog.TimeFormatUnix, nil
+	case benchmark.TimeUnixMs:
+		return phuslog.TimeFormatUnixMs, nil
+	case benchmark.TimeNone:
+		// phuslog always writes the time field
+		return "", fmt.Errorf("%w: %s", benchmark.ErrUnsupportedTimeEncoding, e)
+	}
+	return e.Layout(), nil
+}
+
+func newAdapter(
+	out io.ReadWriter,
+	conf benchmark.Config,
+	async bool,
+) (benchmark.Adapter, error) {
+	format, err := timeFormat(conf.TimeEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst io.Writer = out
+	var flush benchmark.FnFlush
+	if async {
+		dst, flush = newAsyncWriter(out)
+	}
+	w := newWriter(dst, conf)
+	if conf.Clock != nil {
+		w = &clockWriter{
+			next:     w,
+			now:      conf.Clock,
+			encoding: conf.TimeEncoding,
+		}
+	}
+
+	// Initialize logger
+	l := phuslog.Logger{
+		Level:      phuslog.InfoLevel,
+		TimeFormat: format,
+		Writer:     w,
+	}
+
+	a := &adapter{
+		l:           l,
+		with10Exist: l,
+		hooked:      l,
+		sampler:     &benchmark.Sampler{N: benchmark.SampleRate},
+		flush:       flush,
+	}
+
 	fields := benchmark.NewFields10()
-	l := newLogger(out)
-	l.Context = phuslog.NewContext(l.Context[:0]).
+	a.with10Exist.Context = phuslog.NewContext(nil).
 		Str(fields.Name1, fields.Value1).
 		Str(fields.Name2, fields.Value2).
 		Str(fields.Name3, fields.Value3).
@@ -98,20 +379,23 @@ func newInfoWith10Exist(out io.ReadWriter) (
 		Ints(fields.Name9, fields.Value9).
 		Floats64(fields.Name10, fields.Value10).
 		Value()
-	return func(msg string) {
-		l.Info().Msg(msg)
-	}, nil
-}
-
-// Setup initializes the phuslog based logger
-func Setup() benchmark.Setup {
-	return benchmark.Setup{
-		Info:               newInfo,
-		InfoFmt:            newInfoFmt,
-		InfoWithErrorStack: newInfoWithErrorStack,
-		Error:              newError,
-		InfoWith3:          newInfoWith3,
-		InfoWith10:         newInfoWith10,
-		InfoWith10Exist:    newInfoWith10Exist,
-	}
+
+	a.hooked.Writer = &hostnameWriter{next: w}
+
+	a.ctx = context.WithValue(benchmark.NewTraceContext(), ctxKeyLogger{}, &a.l)
+	return a, nil
+}
+
+// New creates a new phuslog based logger adapter
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, false)
+}
+
+// NewAsync creates a new phuslog based logger adapter
+// writing through an asynchronous writer
+func NewAsync(
+	out io.ReadWriter,
+	conf benchmark.Config,
+) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, true)
 }
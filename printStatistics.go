@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"runtime"
 	"time"
@@ -16,25 +17,20 @@ func printStatistics(
 	timeTotal time.Duration,
 	target uint64,
 	concWriters uint,
-	memStatChan chan benchmark.MemStats,
+	conf benchmark.Config,
+	memCheckInterval time.Duration,
 	loggerOrder []string,
 	operationsOrder []string,
 	stats map[string]map[string]benchmark.Statistics,
 ) {
 	numPrint := message.NewPrinter(language.English)
 
-	memStats := <-memStatChan
-
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
 	totalConcWriters := numPrint.Sprintf("%d", concWriters)
 	totalGC := numPrint.Sprintf("%d", m.NumGC)
 	totalMallocs := numPrint.Sprintf("%d", m.Mallocs)
-	maxHeapObj := numPrint.Sprintf("%d", memStats.MaxHeapObjects)
-	heapAllocInc := numPrint.Sprintf("%d", memStats.HeapAllocInc)
-	heapObjInc := numPrint.Sprintf("%d", memStats.HeapObjectsInc)
-	memStatSamples := numPrint.Sprintf("%d", memStats.StatSamples)
 
 	// Print main table
 	{
@@ -44,14 +40,16 @@ func printStatistics(
 		dr := func(k, v string) { tbMain.Append([]string{k, v}) }
 		dr("target", numPrint.Sprintf("%d", target))
 		dr("conc. writers", totalConcWriters)
+		dr("encoding", string(conf.Encoding))
+		dr("timefmt", string(conf.TimeEncoding))
+		dr("clock", string(conf.ClockMode))
+		dr("fields (info_with_n)", numPrint.Sprintf("%d", conf.FieldsN))
+		dr("child fields", numPrint.Sprintf("%d", conf.ChildFields))
+		dr("child messages", numPrint.Sprintf("%d", conf.ChildMessages))
+		dr("mem interval", memCheckInterval.String())
 		dr("", "")
 		dr("time total", timeTotal.String())
-		dr("max heap", humanize.Bytes(memStats.MaxHeapAlloc))
-		dr("max heap obj", maxHeapObj)
-		dr("mem samples", memStatSamples)
-		dr("heap inc", heapAllocInc)
-		dr("heap obj in", heapObjInc)
-		dr("heap sys", humanize.Bytes(memStats.MaxHeapSys))
+		dr("heap sys", humanize.Bytes(m.HeapSys))
 		dr("total alloc", humanize.Bytes(m.TotalAlloc))
 		dr("mallocs", totalMallocs)
 		dr("num-gc", totalGC)
@@ -59,6 +57,74 @@ func printStatistics(
 		tbMain.Render()
 	}
 
+	// Print memory table
+	{
+		tbMem := tablewriter.NewWriter(os.Stdout)
+		tbMem.SetHeader([]string{
+			"logger",
+			"operation",
+			"max heap",
+			"max heap obj",
+			"max live heap",
+			"max heap sys",
+			"heap inc",
+			"heap obj inc",
+			"num-gc",
+			"gc pauses",
+			"gc pause p99",
+			"gc pause max",
+			"sched. lat. p99",
+			"sched. lat. max",
+			"mem samples",
+			"sampling",
+		})
+		tbMem.SetAlignment(tablewriter.ALIGN_LEFT)
+
+		for _, loggerName := range loggerOrder {
+			for _, operation := range operationsOrder {
+				stats := stats[loggerName][operation]
+				if stats.Unsupported {
+					tbMem.Append([]string{
+						loggerName,
+						operation,
+						"unsupported",
+						"", "", "", "", "", "", "", "", "", "", "", "", "",
+					})
+					continue
+				}
+				mem := stats.Memory
+				// The live heap is only known after the first GC completed
+				heapLive := "n/a"
+				if mem.MaxHeapLive != 0 {
+					heapLive = humanize.Bytes(mem.MaxHeapLive)
+				}
+				tbMem.Append([]string{
+					loggerName,
+					operation,
+					humanize.Bytes(mem.MaxHeapAlloc),
+					numPrint.Sprintf("%d", mem.MaxHeapObjects),
+					heapLive,
+					humanize.Bytes(mem.MaxHeapSys),
+					numPrint.Sprintf("%d", mem.HeapAllocInc),
+					numPrint.Sprintf("%d", mem.HeapObjectsInc),
+					numPrint.Sprintf("%d", mem.GCCycles),
+					numPrint.Sprintf("%d", mem.GCPauses),
+					mem.GCPauseP99.String(),
+					mem.GCPauseMax.String(),
+					mem.SchedLatencyP99.String(),
+					mem.SchedLatencyMax.String(),
+					numPrint.Sprintf("%d", mem.StatSamples),
+					fmt.Sprintf(
+						"%s (%.3f%%)",
+						mem.SamplingTime,
+						mem.SamplingOverhead()*100,
+					),
+				})
+			}
+		}
+		tbMem.Render()
+	}
+
 	// Print comparisons table
 	{
 		tbMain := tablewriter.NewWriter(os.Stdout)
@@ -67,22 +133,94 @@ func printStatistics(
 			"operation",
 			"time total",
 			"time avg.",
+			"flush",
 			"written",
+			"emitted",
+			"dropped",
+			"output",
+			"output avg.",
+			"vs. reference",
 		})
 		tbMain.SetAlignment(tablewriter.ALIGN_LEFT)
 
 		for _, loggerName := range loggerOrder {
 			for _, operation := range operationsOrder {
+				reference := stats[referenceLogger][operation]
 				stats := stats[loggerName][operation]
+				if stats.Unsupported {
+					tbMain.Append([]string{
+						loggerName,
+						operation,
+						"unsupported",
+						"", "", "", "", "", "", "", "",
+					})
+					continue
+				}
+				emitted := ""
+				if operation == benchmark.LogOperationInfoSampled {
+					emitted = numPrint.Sprintf("%d", stats.TotalLogsEmitted)
+				}
+				outputAvg := uint64(0)
+				if stats.TotalLogsWritten > 0 {
+					outputAvg = stats.TotalBytesWritten / stats.TotalLogsWritten
+				}
 				tbMain.Append([]string{
 					loggerName,
 					operation,
 					stats.TotalTime.String(),
 					(stats.TotalTime / time.Duration(target)).String(),
+					stats.TotalFlushTime.String(),
 					numPrint.Sprintf("%d", stats.TotalLogsWritten),
+					emitted,
+					numPrint.Sprintf("%d", stats.TotalLogsDropped),
+					humanize.Bytes(stats.TotalBytesWritten),
+					numPrint.Sprintf("%d B", outputAvg),
+					overhead(stats, reference),
 				})
 			}
 		}
 		tbMain.Render()
 	}
+
+	// Print the reasons of unsupported logger/operation pairs
+	{
+		tbUnsupported := tablewriter.NewWriter(os.Stdout)
+		tbUnsupported.SetHeader([]string{
+			"logger",
+			"operation",
+			"unsupported reason",
+		})
+		tbUnsupported.SetAlignment(tablewriter.ALIGN_LEFT)
+		tbUnsupported.SetAutoWrapText(false)
+
+		for _, loggerName := range loggerOrder {
+			for _, operation := range operationsOrder {
+				stats := stats[loggerName][operation]
+				if !stats.Unsupported {
+					continue
+				}
+				tbUnsupported.Append([]string{
+					loggerName,
+					operation,
+					stats.UnsupportedReason,
+				})
+			}
+		}
+		if tbUnsupported.NumLines() > 0 {
+			tbUnsupported.Render()
+		}
+	}
+}
+
+// overhead returns the total time of stats relative to the total time
+// of the reference logger for the same operation. Returns an empty string
+// if the reference logger wasn't run
+func overhead(stats, reference benchmark.Statistics) string {
+	if reference.Unsupported || reference.TotalTime == 0 {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%.2fx",
+		float64(stats.TotalTime)/float64(reference.TotalTime),
+	)
 }
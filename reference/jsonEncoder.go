@@ -0,0 +1,203 @@
+package reference
+
+import (
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a quoted JSON string escaping quotes,
+// backslashes and control characters and replacing invalid UTF-8 bytes
+// with U+FFFD
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	b = appendStringContent(b, s)
+	return append(b, '"')
+}
+
+// appendStringContent appends s escaped but unquoted
+func appendStringContent(b []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' && c < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if c < utf8.RuneSelf {
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(
+					b,
+					'\\', 'u', '0', '0',
+					hexDigits[c>>4], hexDigits[c&0xf],
+				)
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i++
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(b, s[start:]...)
+}
+
+// appendKey appends a comma-prefixed JSON object key
+func appendKey(b []byte, key string) []byte {
+	b = append(b, ',')
+	b = appendString(b, key)
+	return append(b, ':')
+}
+
+// appendFloat64 appends f as a JSON number,
+// NaN and infinities are appended as strings
+func appendFloat64(b []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendString(b, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, 64)
+}
+
+// appendBytesString appends v as a quoted JSON string without copying it
+func appendBytesString(b []byte, v []byte) []byte {
+	return appendString(b, unsafe.String(unsafe.SliceData(v), len(v)))
+}
+
+func appendBool(b []byte, v bool) []byte {
+	return strconv.AppendBool(b, v)
+}
+
+func appendInt(b []byte, v int) []byte {
+	return strconv.AppendInt(b, int64(v), 10)
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	b = append(b, '"')
+	b = t.AppendFormat(b, time.RFC3339Nano)
+	return append(b, '"')
+}
+
+func appendDuration(b []byte, d time.Duration) []byte {
+	return strconv.AppendInt(b, int64(d), 10)
+}
+
+func appendHex(b []byte, v []byte) []byte {
+	b = append(b, '"')
+	for _, c := range v {
+		b = append(b, hexDigits[c>>4], hexDigits[c&0xf])
+	}
+	return append(b, '"')
+}
+
+func appendIP(b []byte, ip net.IP) []byte {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return appendString(b, "")
+	}
+	b = append(b, '"')
+	b = addr.Unmap().AppendTo(b)
+	return append(b, '"')
+}
+
+func appendStrings(b []byte, v []string) []byte {
+	b = append(b, '[')
+	for i, s := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendString(b, s)
+	}
+	return append(b, ']')
+}
+
+func appendInts(b []byte, v []int) []byte {
+	b = append(b, '[')
+	for i, n := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendInt(b, n)
+	}
+	return append(b, ']')
+}
+
+func appendFloat64s(b []byte, v []float64) []byte {
+	b = append(b, '[')
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendFloat64(b, f)
+	}
+	return append(b, ']')
+}
+
+func appendErrors(b []byte, v []error) []byte {
+	b = append(b, '[')
+	for i, err := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendString(b, err.Error())
+	}
+	return append(b, ']')
+}
+
+func appendFields(b []byte, fields benchmark.FieldsN) []byte {
+	for _, f := range fields {
+		b = appendKey(b, f.Name)
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			b = appendString(b, f.String)
+		case benchmark.FieldTypeInt:
+			b = appendInt(b, f.Int)
+		case benchmark.FieldTypeFloat64:
+			b = appendFloat64(b, f.Float64)
+		case benchmark.FieldTypeBool:
+			b = appendBool(b, f.Bool)
+		case benchmark.FieldTypeDuration:
+			b = appendDuration(b, f.Duration)
+		case benchmark.FieldTypeTime:
+			b = appendTime(b, f.Time)
+		}
+	}
+	return b
+}
+
+func appendFields10(b []byte, fields *benchmark.Fields10) []byte {
+	b = appendString(appendKey(b, fields.Name1), fields.Value1)
+	b = appendString(appendKey(b, fields.Name2), fields.Value2)
+	b = appendString(appendKey(b, fields.Name3), fields.Value3)
+	b = appendBool(appendKey(b, fields.Name4), fields.Value4)
+	b = appendString(appendKey(b, fields.Name5), fields.Value5)
+	b = appendInt(appendKey(b, fields.Name6), fields.Value6)
+	b = appendFloat64(appendKey(b, fields.Name7), fields.Value7)
+	b = appendStrings(appendKey(b, fields.Name8), fields.Value8)
+	b = appendInts(appendKey(b, fields.Name9), fields.Value9)
+	return appendFloat64s(appendKey(b, fields.Name10), fields.Value10)
+}
@@ -0,0 +1,6 @@
+//go:build !race
+
+package reference_test
+
+// raceEnabled is set if the race detector is on
+const raceEnabled = false
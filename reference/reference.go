@@ -0,0 +1,230 @@
+package reference
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+// bufPool pools the buffers the records are encoded into
+var bufPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 1024)
+	return &b
+}}
+
+// adapter is a hand-optimized logger adapter appending JSON directly
+// into pooled buffers and writing once per log. It serves as the
+// theoretical floor the real loggers are compared against
+type adapter struct {
+	out io.Writer
+
+	// now provides the time of the records
+	now          func() time.Time
+	timeEncoding benchmark.TimeEncoding
+
+	// with10Exist are the encoded fields of NewFields10
+	with10Exist []byte
+	sampler     *benchmark.Sampler
+	ctx         context.Context
+}
+
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+)
+
+// beginRecord takes a buffer from the pool and appends the time
+// and level fields as well as the key of the message field
+func (a *adapter) beginRecord(level string) *[]byte {
+	buf := bufPool.Get().(*[]byte)
+	b := append((*buf)[:0], '{')
+	if a.timeEncoding != benchmark.TimeNone {
+		b = append(b, `"`+benchmark.FieldTime+`":`...)
+		b = benchmark.AppendTime(b, a.now(), a.timeEncoding)
+		b = append(b, ',')
+	}
+	b = append(b, `"`+benchmark.FieldLevel+`":`...)
+	b = appendString(b, level)
+	*buf = appendKey(b, benchmark.FieldMessage)
+	return buf
+}
+
+// begin begins a record of the given level and message
+func (a *adapter) begin(level, msg string) *[]byte {
+	buf := a.beginRecord(level)
+	*buf = appendString(*buf, msg)
+	return buf
+}
+
+// end closes the record, writes it and returns the buffer to the pool
+func (a *adapter) end(buf *[]byte) {
+	*buf = append(*buf, '}', '\n')
+	_, _ = a.out.Write(*buf)
+	bufPool.Put(buf)
+}
+
+func (a *adapter) Info(msg string) {
+	a.end(a.begin(benchmark.LevelInfo, msg))
+}
+
+func (a *adapter) InfoFmt(msg string, data int) {
+	buf := a.beginRecord(benchmark.LevelInfo)
+	*buf = appendFormatted(*buf, msg, data)
+	a.end(buf)
+}
+
+// appendFormatted appends msg as a quoted JSON string replacing
+// its first %d verb by data. Messages with other verbs fall back to fmt
+func appendFormatted(b []byte, msg string, data int) []byte {
+	for i := 0; i+1 < len(msg); i++ {
+		if msg[i] != '%' {
+			continue
+		}
+		if msg[i+1] != 'd' {
+			break
+		}
+		b = append(b, '"')
+		b = appendStringContent(b, msg[:i])
+		b = strconv.AppendInt(b, int64(data), 10)
+		b = appendStringContent(b, msg[i+2:])
+		return append(b, '"')
+	}
+	return appendString(b, fmt.Sprintf(msg, data))
+}
+
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	*buf = appendString(appendKey(*buf, benchmark.FieldError), err.Error())
+	a.end(buf)
+}
+
+func (a *adapter) Error(msg string) {
+	a.end(a.begin(benchmark.LevelError, msg))
+}
+
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	b := appendString(appendKey(*buf, fields.Name1), fields.Value1)
+	b = appendInt(appendKey(b, fields.Name2), fields.Value2)
+	*buf = appendFloat64(appendKey(b, fields.Name3), fields.Value3)
+	a.end(buf)
+}
+
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	*buf = appendFields10(*buf, fields)
+	a.end(buf)
+}
+
+func (a *adapter) InfoWith10Exist(msg string) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	*buf = append(*buf, a.with10Exist...)
+	a.end(buf)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	*buf = appendFields(*buf, fields)
+	a.end(buf)
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
+) {
+	// Encode the fields of the child logger once
+	child := bufPool.Get().(*[]byte)
+	*child = appendFields((*child)[:0], fields)
+	for i := 0; i < messages; i++ {
+		buf := a.begin(benchmark.LevelInfo, msg)
+		*buf = append(*buf, *child...)
+		a.end(buf)
+	}
+	bufPool.Put(child)
+}
+
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	b := appendTime(appendKey(*buf, fields.Name1), fields.Value1)
+	b = appendDuration(appendKey(b, fields.Name2), fields.Value2)
+	b = appendBytesString(appendKey(b, fields.Name3), fields.Value3)
+	b = appendHex(appendKey(b, fields.Name4), fields.Value4)
+	b = appendIP(appendKey(b, fields.Name5), fields.Value5)
+	*buf = appendErrors(appendKey(b, fields.Name6), fields.Value6)
+	a.end(buf)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	if a.sampler.Sample() {
+		a.end(a.begin(benchmark.LevelInfo, msg))
+	}
+}
+
+// hostnameHook appends the hostname field
+func hostnameHook(b []byte) []byte {
+	return appendString(
+		appendKey(b, benchmark.FieldHostname),
+		benchmark.Hostname,
+	)
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	buf := a.begin(benchmark.LevelInfo, msg)
+	*buf = hostnameHook(*buf)
+	a.end(buf)
+}
+
+type ctxKeyAdapter struct{}
+
+func fromContext(ctx context.Context) *adapter {
+	if a, ok := ctx.Value(ctxKeyAdapter{}).(*adapter); ok {
+		return a
+	}
+	return &adapter{out: io.Discard, now: time.Now}
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	l := fromContext(a.ctx)
+	buf := l.begin(benchmark.LevelInfo, msg)
+	*buf = appendString(
+		appendKey(*buf, benchmark.FieldTraceID),
+		benchmark.TraceIDFromContext(a.ctx),
+	)
+	l.end(buf)
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	// Records are written synchronously
+	return 0, nil
+}
+
+// New creates a new reference logger adapter writing JSON records
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	if conf.Encoding != benchmark.EncodingJSON {
+		return nil, fmt.Errorf(
+			"%w: %s",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+
+	a := &adapter{
+		out:          out,
+		now:          conf.Now,
+		timeEncoding: conf.TimeEncoding,
+		with10Exist:  appendFields10(nil, benchmark.NewFields10()),
+		sampler:      &benchmark.Sampler{N: benchmark.SampleRate},
+	}
+	a.ctx = context.WithValue(benchmark.NewTraceContext(), ctxKeyAdapter{}, a)
+	return a, nil
+}
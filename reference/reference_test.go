@@ -0,0 +1,96 @@
+package reference_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/reference"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// discard is an output discarding the records without allocating
+type discard struct{}
+
+func (discard) Read([]byte) (int, error)    { return 0, io.EOF }
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestZeroAllocations(t *testing.T) {
+	if raceEnabled {
+		// The race detector makes sync.Pool drop items at random
+		t.Skip("allocations aren't reproducible with the race detector")
+	}
+	conf := benchmark.DefaultConfig()
+	a, err := reference.New(discard{}, conf)
+	require.NoError(t, err)
+
+	errVal := errors.New("error with stack trace")
+	fields3 := benchmark.NewFields3()
+	fields10 := benchmark.NewFields10()
+	fieldsN := benchmark.NewFieldsN(conf.FieldsN, conf.FieldTypes)
+	childFields := benchmark.NewFieldsN(
+		conf.ChildFields,
+		[]benchmark.FieldType{benchmark.FieldTypeString},
+	)
+	fieldsTypes := benchmark.NewFieldsTypes()
+
+	operations := map[string]func(){
+		benchmark.LogOperationInfo: func() { a.Info("information") },
+		benchmark.LogOperationInfoFmt: func() {
+			a.InfoFmt("information %d", 42)
+		},
+		benchmark.LogOperationInfoWithErrorStack: func() {
+			a.InfoWithErrorStack("information", errVal)
+		},
+		benchmark.LogOperationError: func() { a.Error("error message") },
+		benchmark.LogOperationInfoWith3: func() {
+			a.InfoWith3("information", fields3)
+		},
+		benchmark.LogOperationInfoWith10: func() {
+			a.InfoWith10("information", fields10)
+		},
+		benchmark.LogOperationInfoWith10Exist: func() {
+			a.InfoWith10Exist("information")
+		},
+		benchmark.LogOperationInfoWithN: func() {
+			a.(benchmark.FieldsLogger).InfoWithN("information", fieldsN)
+		},
+		benchmark.LogOperationInfoChildLogger: func() {
+			a.(benchmark.ChildLogger).InfoChildLogger(
+				"information",
+				childFields,
+				conf.ChildMessages,
+			)
+		},
+		benchmark.LogOperationInfoWithContext: func() {
+			a.(benchmark.ContextLogger).InfoWithContext("information")
+		},
+		benchmark.LogOperationInfoWithTypes: func() {
+			a.(benchmark.TypesLogger).InfoWithTypes("information", fieldsTypes)
+		},
+		benchmark.LogOperationInfoSampled: func() {
+			a.(benchmark.SampledLogger).InfoSampled("information")
+		},
+		benchmark.LogOperationInfoWithHook: func() {
+			a.(benchmark.HookLogger).InfoWithHook("information")
+		},
+	}
+	for _, operation := range []string{
+		benchmark.LogOperationInfoLarge1K,
+		benchmark.LogOperationInfoLarge16K,
+		benchmark.LogOperationInfoLarge1M,
+		benchmark.LogOperationInfoEscape,
+		benchmark.LogOperationInfoUnicode,
+		benchmark.LogOperationInfoInvalidUTF8,
+	} {
+		msg := benchmark.PayloadMessage(operation)
+		operations[operation] = func() { a.Info(msg) }
+	}
+
+	for operation, fn := range operations {
+		t.Run(operation, func(t *testing.T) {
+			require.Zero(t, testing.AllocsPerRun(100, fn))
+		})
+	}
+}
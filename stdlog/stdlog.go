@@ -0,0 +1,268 @@
+package stdlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+// logger writes hand-assembled JSON records through a log.Logger
+type logger struct {
+	l *log.Logger
+
+	// now provides the time of the records
+	now          func() time.Time
+	timeEncoding benchmark.TimeEncoding
+
+	// fields are the encoded fields attached to all records
+	fields []byte
+
+	// hook, if not nil, appends additional fields to all records
+	hook func(b []byte) []byte
+}
+
+// appendJSON appends the JSON encoding of v to b
+func appendJSON(b []byte, v interface{}) []byte {
+	j, err := json.Marshal(v)
+	if err != nil {
+		// Encode the error instead of the unsupported value
+		j, _ = json.Marshal(err.Error())
+	}
+	return append(b, j...)
+}
+
+// appendField appends a comma-prefixed JSON key-value pair to b
+func appendField(b []byte, key string, value interface{}) []byte {
+	b = append(b, ',')
+	b = appendJSON(b, key)
+	b = append(b, ':')
+	return appendJSON(b, value)
+}
+
+func appendFields(b []byte, fields benchmark.FieldsN) []byte {
+	for _, f := range fields {
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			b = appendField(b, f.Name, f.String)
+		case benchmark.FieldTypeInt:
+			b = appendField(b, f.Name, f.Int)
+		case benchmark.FieldTypeFloat64:
+			b = appendField(b, f.Name, f.Float64)
+		case benchmark.FieldTypeBool:
+			b = appendField(b, f.Name, f.Bool)
+		case benchmark.FieldTypeDuration:
+			b = appendField(b, f.Name, f.Duration)
+		case benchmark.FieldTypeTime:
+			b = appendField(b, f.Name, f.Time)
+		}
+	}
+	return b
+}
+
+func appendFields10(b []byte, fields *benchmark.Fields10) []byte {
+	b = appendField(b, fields.Name1, fields.Value1)
+	b = appendField(b, fields.Name2, fields.Value2)
+	b = appendField(b, fields.Name3, fields.Value3)
+	b = appendField(b, fields.Name4, fields.Value4)
+	b = appendField(b, fields.Name5, fields.Value5)
+	b = appendField(b, fields.Name6, fields.Value6)
+	b = appendField(b, fields.Name7, fields.Value7)
+	b = appendField(b, fields.Name8, fields.Value8)
+	b = appendField(b, fields.Name9, fields.Value9)
+	return appendField(b, fields.Name10, fields.Value10)
+}
+
+// with returns a child logger with the given encoded fields attached
+func (l logger) with(fields []byte) logger {
+	c := l
+	c.fields = append(append([]byte(nil), l.fields...), fields...)
+	return c
+}
+
+// write writes a record of the given level and message
+// with the given encoded fields attached
+func (l logger) write(level, msg string, fields []byte) {
+	b := []byte{'{'}
+	if l.timeEncoding != benchmark.TimeNone {
+		b = appendJSON(b, benchmark.FieldTime)
+		b = append(b, ':')
+		b = benchmark.AppendTime(b, l.now(), l.timeEncoding)
+		b = append(b, ',')
+	}
+	b = appendJSON(b, benchmark.FieldLevel)
+	b = append(b, ':')
+	b = appendJSON(b, level)
+	b = appendField(b, benchmark.FieldMessage, msg)
+	b = append(b, l.fields...)
+	b = append(b, fields...)
+	if l.hook != nil {
+		b = l.hook(b)
+	}
+	b = append(b, '}')
+	l.l.Print(string(b))
+}
+
+// adapter is the standard library log package based logger adapter
+type adapter struct {
+	l logger
+
+	// with10Exist and hooked are derived from l
+	with10Exist logger
+	hooked      logger
+	sampler     *benchmark.Sampler
+	ctx         context.Context
+}
+
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+)
+
+func (a *adapter) Info(msg string) {
+	a.l.write(benchmark.LevelInfo, msg, nil)
+}
+
+func (a *adapter) InfoFmt(msg string, data int) {
+	a.l.write(benchmark.LevelInfo, fmt.Sprintf(msg, data), nil)
+}
+
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	a.l.write(
+		benchmark.LevelInfo,
+		msg,
+		appendField(nil, benchmark.FieldError, err.Error()),
+	)
+}
+
+func (a *adapter) Error(msg string) {
+	a.l.write(benchmark.LevelError, msg, nil)
+}
+
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	b := appendField(nil, fields.Name1, fields.Value1)
+	b = appendField(b, fields.Name2, fields.Value2)
+	b = appendField(b, fields.Name3, fields.Value3)
+	a.l.write(benchmark.LevelInfo, msg, b)
+}
+
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	a.l.write(benchmark.LevelInfo, msg, appendFields10(nil, fields))
+}
+
+func (a *adapter) InfoWith10Exist(msg string) {
+	a.with10Exist.write(benchmark.LevelInfo, msg, nil)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	a.l.write(benchmark.LevelInfo, msg, appendFields(nil, fields))
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
+) {
+	c := a.l.with(appendFields(nil, fields))
+	for i := 0; i < messages; i++ {
+		c.write(benchmark.LevelInfo, msg, nil)
+	}
+}
+
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	errs := make([]string, len(fields.Value6))
+	for i, err := range fields.Value6 {
+		errs[i] = err.Error()
+	}
+	b := appendField(nil, fields.Name1, fields.Value1)
+	b = appendField(b, fields.Name2, fields.Value2)
+	b = appendField(b, fields.Name3, string(fields.Value3))
+	b = appendField(b, fields.Name4, fields.Value4)
+	b = appendField(b, fields.Name5, fields.Value5)
+	b = appendField(b, fields.Name6, errs)
+	a.l.write(benchmark.LevelInfo, msg, b)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	// The log package doesn't provide a sampler
+	if a.sampler.Sample() {
+		a.l.write(benchmark.LevelInfo, msg, nil)
+	}
+}
+
+// hostnameHook appends the hostname field
+func hostnameHook(b []byte) []byte {
+	return appendField(b, benchmark.FieldHostname, benchmark.Hostname)
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	a.hooked.write(benchmark.LevelInfo, msg, nil)
+}
+
+type ctxKeyLogger struct{}
+
+func fromContext(ctx context.Context) logger {
+	if l, ok := ctx.Value(ctxKeyLogger{}).(logger); ok {
+		return l
+	}
+	return logger{l: log.Default(), now: time.Now}
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	fromContext(a.ctx).write(
+		benchmark.LevelInfo,
+		msg,
+		appendField(
+			nil,
+			benchmark.FieldTraceID,
+			benchmark.TraceIDFromContext(a.ctx),
+		),
+	)
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	// log.Logger writes synchronously
+	return 0, nil
+}
+
+// New creates a new standard library log package based logger adapter
+// writing JSON records
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	if conf.Encoding != benchmark.EncodingJSON {
+		return nil, fmt.Errorf(
+			"%w: %s",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+
+	// Initialize logger
+	l := logger{
+		l:            log.New(out, "", 0),
+		now:          conf.Now,
+		timeEncoding: conf.TimeEncoding,
+	}
+
+	hooked := l
+	hooked.hook = hostnameHook
+
+	return &adapter{
+		l:           l,
+		with10Exist: l.with(appendFields10(nil, benchmark.NewFields10())),
+		hooked:      hooked,
+		sampler:     &benchmark.Sampler{N: benchmark.SampleRate},
+		ctx: context.WithValue(
+			benchmark.NewTraceContext(),
+			ctxKeyLogger{},
+			l,
+		),
+	}, nil
+}
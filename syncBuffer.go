@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+)
+
+// SyncBuffer is a thread-safe buffer implementing the io.ReadWriter interface
+type SyncBuffer struct {
+	m sync.Mutex
+	b bytes.Buffer
+}
+
+func (b *SyncBuffer) Read(p []byte) (n int, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Read(p)
+}
+
+func (b *SyncBuffer) Write(p []byte) (n int, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *SyncBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.String()
+}
+
+// Len returns the number of unread bytes
+func (b *SyncBuffer) Len() int {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Len()
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+// Timeline export formats
+const (
+	timelineCSV  = "csv"
+	timelineJSON = "json"
+)
+
+// timelineSeries is the exported memory timeline of a logger and operation.
+// Unsupported logger/operation pairs are exported without samples
+type timelineSeries struct {
+	Unsupported bool                  `json:"unsupported"`
+	Reason      string                `json:"reason,omitempty"`
+	Samples     []benchmark.MemSample `json:"samples"`
+}
+
+// newTimelineSeries returns the timeline series of the given statistics
+func newTimelineSeries(s benchmark.Statistics) timelineSeries {
+	if s.Unsupported {
+		return timelineSeries{
+			Unsupported: true,
+			Reason:      s.UnsupportedReason,
+		}
+	}
+	return timelineSeries{Samples: s.Memory.Timeline}
+}
+
+// writeTimelineCSV writes the samples as CSV with a header line.
+// Unsupported series are written as an unsupported and a reason column
+// instead of the sample columns
+func writeTimelineCSV(w io.Writer, series timelineSeries) error {
+	cw := csv.NewWriter(w)
+	if series.Unsupported {
+		return cw.WriteAll([][]string{
+			{"unsupported", "reason"},
+			{"true", series.Reason},
+		})
+	}
+	if err := cw.Write([]string{
+		"time_ns",
+		"heap_alloc",
+		"heap_objects",
+		"heap_live",
+		"gc_cycles",
+	}); err != nil {
+		return err
+	}
+	for _, s := range series.Samples {
+		if err := cw.Write([]string{
+			strconv.FormatInt(int64(s.Time), 10),
+			strconv.FormatUint(s.HeapAlloc, 10),
+			strconv.FormatUint(s.HeapObjects, 10),
+			strconv.FormatUint(s.HeapLive, 10),
+			strconv.FormatUint(s.GCCycles, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTimelineJSON writes the series as a JSON object
+func writeTimelineJSON(w io.Writer, series timelineSeries) error {
+	if series.Samples == nil {
+		series.Samples = []benchmark.MemSample{}
+	}
+	return json.NewEncoder(w).Encode(series)
+}
+
+// timelineExporter returns the function exporting timelines
+// in the given format
+func timelineExporter(
+	format string,
+) (func(io.Writer, timelineSeries) error, error) {
+	switch format {
+	case timelineCSV:
+		return writeTimelineCSV, nil
+	case timelineJSON:
+		return writeTimelineJSON, nil
+	}
+	return nil, fmt.Errorf("unknown timeline format: %q", format)
+}
+
+// Dimensions of the timeline chart
+const (
+	svgWidth       = 800
+	svgPanelHeight = 200
+	svgMarginLeft  = 80
+	svgMarginRight = 20
+	svgMarginTop   = 40
+	svgPanelGap    = 40
+	svgPlotWidth   = svgWidth - svgMarginLeft - svgMarginRight
+	svgHeight      = svgMarginTop + 2*svgPanelHeight + 2*svgPanelGap
+)
+
+// svgSeries is a line of a timeline chart panel
+type svgSeries struct {
+	Name  string
+	Color string
+	Value func(benchmark.MemSample) uint64
+}
+
+// escapeXML escapes s for use in XML text and attributes
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// renderTimelinePanel renders a chart panel of the given series
+// at the given vertical offset marking GC cycles by vertical lines
+func renderTimelinePanel(
+	b *strings.Builder,
+	top int,
+	timeline []benchmark.MemSample,
+	series []svgSeries,
+	formatValue func(uint64) string,
+) {
+	maxTime := timeline[len(timeline)-1].Time
+	if maxTime <= 0 {
+		maxTime = 1
+	}
+	var maxValue uint64 = 1
+	for _, s := range timeline {
+		for _, sr := range series {
+			if v := sr.Value(s); v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	x := func(t time.Duration) float64 {
+		return svgMarginLeft + float64(t)/float64(maxTime)*svgPlotWidth
+	}
+	y := func(v uint64) float64 {
+		return float64(top+svgPanelHeight) -
+			float64(v)/float64(maxValue)*svgPanelHeight
+	}
+
+	// Axes and scale labels
+	fmt.Fprintf(
+		b,
+		`<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#999"/>`+"\n",
+		svgMarginLeft, top, svgPlotWidth, svgPanelHeight,
+	)
+	fmt.Fprintf(
+		b,
+		`<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n",
+		svgMarginLeft-5, top+10, escapeXML(formatValue(maxValue)),
+	)
+	fmt.Fprintf(
+		b,
+		`<text x="%d" y="%d" text-anchor="end">0</text>`+"\n",
+		svgMarginLeft-5, top+svgPanelHeight,
+	)
+	fmt.Fprintf(
+		b,
+		`<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n",
+		svgMarginLeft+svgPlotWidth, top+svgPanelHeight+15, maxTime,
+	)
+
+	// GC cycles
+	for i := 1; i < len(timeline); i++ {
+		if timeline[i].GCCycles == timeline[i-1].GCCycles {
+			continue
+		}
+		fmt.Fprintf(
+			b,
+			`<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n",
+			x(timeline[i].Time), top, x(timeline[i].Time), top+svgPanelHeight,
+		)
+	}
+
+	// Series and legend
+	for i, sr := range series {
+		points := make([]string, len(timeline))
+		for j, s := range timeline {
+			points[j] = fmt.Sprintf("%.1f,%.1f", x(s.Time), y(sr.Value(s)))
+		}
+		fmt.Fprintf(
+			b,
+			`<polyline points="%s" fill="none" stroke="%s"/>`+"\n",
+			strings.Join(points, " "), sr.Color,
+		)
+		fmt.Fprintf(
+			b,
+			`<text x="%d" y="%d" fill="%s">%s</text>`+"\n",
+			svgMarginLeft+10+i*120, top-5, sr.Color, escapeXML(sr.Name),
+		)
+	}
+}
+
+// renderTimelineSVG renders a line chart of the heap size
+// and the number of heap objects over time as SVG
+func renderTimelineSVG(
+	w io.Writer,
+	title string,
+	timeline []benchmark.MemSample,
+) error {
+	var b strings.Builder
+	fmt.Fprintf(
+		&b,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" `+
+			`font-family="sans-serif" font-size="12">`+"\n",
+		svgWidth, svgHeight,
+	)
+	fmt.Fprintf(
+		&b,
+		`<text x="%d" y="20" font-size="14">%s</text>`+"\n",
+		svgMarginLeft, escapeXML(title+" (GC cycles in gray)"),
+	)
+	if len(timeline) > 0 {
+		renderTimelinePanel(&b, svgMarginTop, timeline, []svgSeries{
+			{
+				Name:  "heap",
+				Color: "#d62728",
+				Value: func(s benchmark.MemSample) uint64 { return s.HeapAlloc },
+			},
+			{
+				Name:  "live heap",
+				Color: "#1f77b4",
+				Value: func(s benchmark.MemSample) uint64 { return s.HeapLive },
+			},
+		}, humanize.Bytes)
+		renderTimelinePanel(
+			&b,
+			svgMarginTop+svgPanelHeight+svgPanelGap,
+			timeline,
+			[]svgSeries{{
+				Name:  "heap objects",
+				Color: "#2ca02c",
+				Value: func(s benchmark.MemSample) uint64 { return s.HeapObjects },
+			}},
+			func(v uint64) string { return humanize.Comma(int64(v)) },
+		)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFile creates the file at the given path
+// and writes its contents using write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeTimelines exports the memory timeline of each logger and operation
+// to <dir>/<logger>/<operation>.<format> and renders it to an SVG chart
+// next to it. Unsupported logger/operation pairs are exported
+// marked as unsupported and not rendered
+func writeTimelines(
+	dir string,
+	format string,
+	stats map[string]map[string]benchmark.Statistics,
+) error {
+	export, err := timelineExporter(format)
+	if err != nil {
+		return err
+	}
+
+	for loggerName, operations := range stats {
+		loggerDir := filepath.Join(dir, loggerName)
+		if err := os.MkdirAll(loggerDir, 0o755); err != nil {
+			return fmt.Errorf("creating timeline directory: %w", err)
+		}
+		for operation, s := range operations {
+			path := filepath.Join(loggerDir, operation+"."+format)
+			if err := writeFile(path, func(w io.Writer) error {
+				return export(w, newTimelineSeries(s))
+			}); err != nil {
+				return fmt.Errorf("exporting timeline: %w", err)
+			}
+			if s.Unsupported {
+				continue
+			}
+
+			path = filepath.Join(loggerDir, operation+".svg")
+			title := loggerName + ": " + operation
+			if err := writeFile(path, func(w io.Writer) error {
+				return renderTimelineSVG(w, title, s.Memory.Timeline)
+			}); err != nil {
+				return fmt.Errorf("rendering timeline: %w", err)
+			}
+		}
+	}
+	return nil
+}
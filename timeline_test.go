@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/stretchr/testify/require"
+)
+
+var testTimeline = []benchmark.MemSample{
+	{Time: time.Millisecond, HeapAlloc: 2048, HeapObjects: 10, HeapLive: 1024},
+	{Time: 2 * time.Millisecond, HeapAlloc: 4096, HeapObjects: 30, HeapLive: 1024},
+	{
+		Time:        3 * time.Millisecond,
+		HeapAlloc:   1024,
+		HeapObjects: 5,
+		HeapLive:    512,
+		GCCycles:    1,
+	},
+}
+
+func TestWriteTimelineCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeTimelineCSV(&buf, timelineSeries{
+		Samples: testTimeline,
+	}))
+	require.Equal(
+		t,
+		"time_ns,heap_alloc,heap_objects,heap_live,gc_cycles\n"+
+			"1000000,2048,10,1024,0\n"+
+			"2000000,4096,30,1024,0\n"+
+			"3000000,1024,5,512,1\n",
+		buf.String(),
+	)
+
+	buf.Reset()
+	require.NoError(t, writeTimelineCSV(&buf, timelineSeries{
+		Unsupported: true,
+		Reason:      "unsupported operation",
+	}))
+	require.Equal(
+		t,
+		"unsupported,reason\n"+
+			"true,unsupported operation\n",
+		buf.String(),
+	)
+}
+
+func TestWriteTimelineJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeTimelineJSON(&buf, timelineSeries{
+		Samples: testTimeline,
+	}))
+	var decoded timelineSeries
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, timelineSeries{Samples: testTimeline}, decoded)
+
+	buf.Reset()
+	require.NoError(t, writeTimelineJSON(&buf, timelineSeries{
+		Unsupported: true,
+		Reason:      "unsupported operation",
+	}))
+	require.Equal(
+		t,
+		`{"unsupported":true,"reason":"unsupported operation","samples":[]}`+"\n",
+		buf.String(),
+	)
+}
+
+// svgElements parses the SVG document and counts its elements by name
+func svgElements(t *testing.T, doc string) map[string]int {
+	elements := map[string]int{}
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return elements
+		}
+		require.NoError(t, err)
+		if el, ok := tok.(xml.StartElement); ok {
+			elements[el.Name.Local]++
+		}
+	}
+}
+
+func TestRenderTimelineSVG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, renderTimelineSVG(&buf, "<zap> & info", testTimeline))
+	elements := svgElements(t, buf.String())
+	require.Equal(t, 1, elements["svg"])
+	require.Equal(t, 3, elements["polyline"])
+	// One GC cycle is marked in each of the 2 panels
+	require.Equal(t, 2, elements["line"])
+	require.Contains(t, buf.String(), "&lt;zap&gt; &amp; info")
+
+	buf.Reset()
+	require.NoError(t, renderTimelineSVG(&buf, "empty", nil))
+	require.Equal(t, 0, svgElements(t, buf.String())["polyline"])
+}
+
+func TestWriteTimelines(t *testing.T) {
+	dir := t.TempDir()
+	stats := map[string]map[string]benchmark.Statistics{
+		"zap": {
+			benchmark.LogOperationInfo: {
+				Memory: benchmark.MemStats{Timeline: testTimeline},
+			},
+			benchmark.LogOperationInfoWithHook: {
+				Unsupported:       true,
+				UnsupportedReason: "unsupported operation",
+			},
+		},
+	}
+	require.NoError(t, writeTimelines(dir, timelineCSV, stats))
+
+	for _, name := range []string{"info.csv", "info.svg", "info_with_hook.csv"} {
+		_, err := os.Stat(filepath.Join(dir, "zap", name))
+		require.NoError(t, err, name)
+	}
+	unsupported, err := os.ReadFile(
+		filepath.Join(dir, "zap", "info_with_hook.csv"),
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"unsupported,reason\ntrue,unsupported operation\n",
+		string(unsupported),
+	)
+	_, err = os.Stat(filepath.Join(dir, "zap", "info_with_hook.svg"))
+	require.True(t, os.IsNotExist(err))
+
+	require.Error(t, writeTimelines(dir, "xml", stats))
+}
@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LineReport summarizes the validation
+// of a log output of one JSON record per line
+type LineReport struct {
+	// Lines is the number of non-empty lines read
+	Lines int
+
+	// Corrupted is the number of lines that aren't a complete JSON object,
+	// which is the case if records were interleaved or partially written
+	Corrupted int
+
+	// Invalid is the number of complete records failing validation
+	Invalid int
+
+	// FirstErr is the error of the first corrupted or invalid line, if any
+	FirstErr error
+}
+
+// Lines reads all lines from r checking that each of them is a complete
+// JSON object and validating the decoded records with validate
+func Lines(
+	r io.Reader,
+	validate func(fields map[string]interface{}) error,
+) (LineReport, error) {
+	var report LineReport
+	s := bufio.NewScanner(r)
+	// Allow for records of the large payload operations
+	s.Buffer(nil, 16*1024*1024)
+	for s.Scan() {
+		line := s.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		report.Lines++
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			report.Corrupted++
+			if report.FirstErr == nil {
+				report.FirstErr = fmt.Errorf(
+					"line %d: %w",
+					report.Lines,
+					err,
+				)
+			}
+			continue
+		}
+		if err := validate(fields); err != nil {
+			report.Invalid++
+			if report.FirstErr == nil {
+				report.FirstErr = fmt.Errorf("line %d: %w", report.Lines, err)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return report, fmt.Errorf("reading lines: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,76 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Schema is a compiled JSON Schema log records are validated against
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// LoadSchema compiles the JSON Schema file at the given path.
+// Formats such as date-time are asserted regardless of the draft
+func LoadSchema(path string) (*Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.AssertFormat = true
+	s, err := c.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema: %w", err)
+	}
+	return &Schema{schema: s}, nil
+}
+
+// Validate validates a decoded record against the schema
+func (s *Schema) Validate(fields map[string]interface{}) error {
+	return s.schema.Validate(fields)
+}
+
+// Report summarizes the validation of the records of a log output
+type Report struct {
+	// Records is the number of records validated
+	Records int
+
+	// Invalid is the number of records failing validation
+	Invalid int
+
+	// FirstErr is the validation error of the first invalid record, if any
+	FirstErr error
+}
+
+// Records decodes all JSON records read from r and validates each
+// of them with validate. Returns an error along with the report
+// of the records decoded so far if r contains malformed JSON
+func Records(
+	r io.Reader,
+	validate func(fields map[string]interface{}) error,
+) (Report, error) {
+	var report Report
+	dec := json.NewDecoder(r)
+	for {
+		var fields map[string]interface{}
+		err := dec.Decode(&fields)
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf(
+				"decoding record %d: %w",
+				report.Records+1,
+				err,
+			)
+		}
+		report.Records++
+		if err := validate(fields); err != nil {
+			report.Invalid++
+			if report.FirstErr == nil {
+				report.FirstErr = err
+			}
+		}
+	}
+}
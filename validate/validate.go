@@ -0,0 +1,387 @@
+// Package validate provides validators of decoded log records
+// and JSON Schema validation of log output
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+// FV represents a mapping between field names
+// and the according list validators
+type FV map[string]func(interface{}) error
+
+// Validate validates a decoded record. Returns an error
+// if a field is missing or its value is invalid
+func (fv FV) Validate(fields map[string]interface{}) error {
+	names := make([]string, 0, len(fv))
+	for name := range fv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("missing field %q", name)
+		}
+		if err := fv[name](val); err != nil {
+			return fmt.Errorf("invalid value for field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func expectString(actual interface{}) (string, error) {
+	val, ok := actual.(string)
+	if !ok {
+		return "", fmt.Errorf(
+			"unexpected field type (expected: string; got: %s)",
+			reflect.TypeOf(actual),
+		)
+	}
+	return val, nil
+}
+
+func expectBool(actual interface{}) (bool, error) {
+	val, ok := actual.(bool)
+	if !ok {
+		return false, fmt.Errorf(
+			"unexpected field type (expected: bool; got: %s)",
+			reflect.TypeOf(actual),
+		)
+	}
+	return val, nil
+}
+
+func expectFloat64(actual interface{}) (float64, error) {
+	val, ok := actual.(float64)
+	if !ok {
+		return 0, fmt.Errorf(
+			"unexpected field type (expected: int; got: %s)",
+			reflect.TypeOf(actual),
+		)
+	}
+	return val, nil
+}
+
+// Number validates a number of any value. It's used for values
+// whose unit differs between loggers, such as durations
+func Number(actual interface{}) error {
+	_, err := expectFloat64(actual)
+	return err
+}
+
+// String validates a string of any value
+func String(actual interface{}) error {
+	_, err := expectString(actual)
+	return err
+}
+
+// Time validates an RFC 3339 timestamp
+func Time(actual interface{}) error {
+	val, err := expectString(actual)
+	if err != nil {
+		return err
+	}
+
+	_, err = time.Parse(time.RFC3339, val)
+	return err
+}
+
+// Level returns a validator of the given level
+func Level(expected string) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectString(actual)
+		if err != nil {
+			return err
+		}
+		if val != expected {
+			return fmt.Errorf(
+				"mismatching level: (expected: %q, got: %q)",
+				expected,
+				val,
+			)
+		}
+		return nil
+	}
+}
+
+// Text returns a validator of the given text
+func Text(expected string) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectString(actual)
+		if err != nil {
+			return err
+		}
+		if val != expected {
+			return fmt.Errorf(
+				"mismatching text: (expected: %q, got: %q)",
+				expected,
+				val,
+			)
+		}
+		return nil
+	}
+}
+
+// Bool returns a validator of the given bool
+func Bool(expected bool) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectBool(actual)
+		if err != nil {
+			return err
+		}
+		if val != expected {
+			return fmt.Errorf("mismatching bool: (expected: %t)", expected)
+		}
+		return nil
+	}
+}
+
+// Int returns a validator of the given int
+func Int(expected int) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectFloat64(actual)
+		if err != nil {
+			return err
+		}
+		if val != float64(expected) {
+			return fmt.Errorf(
+				"mismatching int: (expected: %d, got: %f)",
+				expected,
+				val,
+			)
+		}
+		return nil
+	}
+}
+
+// Float64 returns a validator of the given float64
+func Float64(expected float64) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectFloat64(actual)
+		if err != nil {
+			return err
+		}
+		if val != expected {
+			return fmt.Errorf(
+				"mismatching float64: (expected: %f, got: %f)",
+				expected,
+				val,
+			)
+		}
+		return nil
+	}
+}
+
+// expectLen returns an error if the array doesn't have n items
+func expectLen(actual []interface{}, n int) error {
+	if len(actual) != n {
+		return fmt.Errorf(
+			"mismatching array length (expected: %d, got: %d)",
+			n,
+			len(actual),
+		)
+	}
+	return nil
+}
+
+// Strings returns a validator of the given list of strings
+func Strings(expected []string) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Errorf(
+				"unexpected field type (expected: []string; got: %s)",
+				reflect.TypeOf(actual),
+			)
+		}
+		if err := expectLen(val, len(expected)); err != nil {
+			return err
+		}
+		for i, val := range val {
+			val, err := expectString(val)
+			if err != nil {
+				return err
+			}
+			expected := expected[i]
+			if expected != val {
+				return fmt.Errorf(
+					"mismatching array item: (expected: %s, got: %s)",
+					expected,
+					val,
+				)
+			}
+		}
+		return nil
+	}
+}
+
+// Ints returns a validator of the given list of ints
+func Ints(expected []int) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Errorf(
+				"unexpected field type (expected: []int; got: %s)",
+				reflect.TypeOf(actual),
+			)
+		}
+		if err := expectLen(val, len(expected)); err != nil {
+			return err
+		}
+		for i, val := range val {
+			val, err := expectFloat64(val)
+			if err != nil {
+				return err
+			}
+			expected := expected[i]
+
+			if float64(expected) != val {
+				return fmt.Errorf(
+					"mismatching array item: (expected: %d, got: %f)",
+					expected,
+					val,
+				)
+			}
+		}
+		return nil
+	}
+}
+
+// Float64s returns a validator of the given list of float64s
+func Float64s(expected []float64) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Errorf(
+				"unexpected field type (expected: []interface{}; got: %s)",
+				reflect.TypeOf(actual),
+			)
+		}
+		if err := expectLen(val, len(expected)); err != nil {
+			return err
+		}
+		for i, val := range val {
+			val, err := expectFloat64(val)
+			if err != nil {
+				return err
+			}
+			expected := expected[i]
+
+			if float64(expected) != val {
+				return fmt.Errorf(
+					"mismatching array item: (expected: %f, got: %f)",
+					expected,
+					val,
+				)
+			}
+		}
+		return nil
+	}
+}
+
+// RoundTrip returns a validator of a message round-tripping to the original.
+// Invalid UTF-8 is expected to be replaced by U+FFFD byte by byte,
+// which is what the loggers and encoding/json do
+func RoundTrip(original string) func(interface{}) error {
+	expected := string([]rune(original))
+	return func(actual interface{}) error {
+		val, err := expectString(actual)
+		if err != nil {
+			return err
+		}
+		if val != expected {
+			return fmt.Errorf(
+				"message doesn't round-trip: (expected: %q, got: %q)",
+				truncate(expected, 64),
+				truncate(val, 64),
+			)
+		}
+		return nil
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
+
+// TimeEqual returns a validator of an RFC 3339 timestamp
+// equal to the given time
+func TimeEqual(expected time.Time) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, err := expectString(actual)
+		if err != nil {
+			return err
+		}
+		tm, err := time.Parse(time.RFC3339Nano, val)
+		if err != nil {
+			return err
+		}
+		if !tm.Equal(expected) {
+			return fmt.Errorf(
+				"mismatching time: (expected: %s, got: %s)",
+				expected.Format(time.RFC3339Nano),
+				tm.Format(time.RFC3339Nano),
+			)
+		}
+		return nil
+	}
+}
+
+// ErrorObjects returns a validator of a list of errors
+// encoded as objects with an error field each
+func ErrorObjects(expected []string) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, ok := actual.([]interface{})
+		if !ok || len(val) != len(expected) {
+			return fmt.Errorf(
+				"unexpected field type (expected: [%d]object; got: %s)",
+				len(expected),
+				reflect.TypeOf(actual),
+			)
+		}
+		for i, val := range val {
+			obj, ok := val.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf(
+					"unexpected array item type (expected: object; got: %s)",
+					reflect.TypeOf(val),
+				)
+			}
+			if err := Text(expected[i])(
+				obj[benchmark.FieldError],
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// EmptyObjects returns a validator of a list of n empty objects
+func EmptyObjects(n int) func(interface{}) error {
+	return func(actual interface{}) error {
+		val, ok := actual.([]interface{})
+		if !ok || len(val) != n {
+			return fmt.Errorf(
+				"unexpected field type (expected: [%d]object; got: %s)",
+				n,
+				reflect.TypeOf(actual),
+			)
+		}
+		for _, val := range val {
+			if obj, ok := val.(map[string]interface{}); !ok || len(obj) != 0 {
+				return fmt.Errorf("unexpected array item: %v", val)
+			}
+		}
+		return nil
+	}
+}
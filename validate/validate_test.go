@@ -0,0 +1,138 @@
+package validate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFVValidate(t *testing.T) {
+	fv := validate.FV{
+		benchmark.FieldTime:    validate.Time,
+		benchmark.FieldLevel:   validate.Level(benchmark.LevelInfo),
+		benchmark.FieldMessage: validate.Text("information"),
+	}
+
+	require.NoError(t, fv.Validate(map[string]interface{}{
+		"time":    "2006-01-02T15:04:05Z",
+		"level":   "info",
+		"message": "information",
+		"extra":   1.0,
+	}))
+	require.Error(t, fv.Validate(map[string]interface{}{
+		"time":  "2006-01-02T15:04:05Z",
+		"level": "info",
+	}))
+	require.Error(t, fv.Validate(map[string]interface{}{
+		"time":    "2006-01-02T15:04:05Z",
+		"level":   "error",
+		"message": "information",
+	}))
+}
+
+func TestArrays(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		validate func(interface{}) error
+		valid    []interface{}
+	}{
+		{
+			"strings",
+			validate.Strings([]string{"a", "b"}),
+			[]interface{}{"a", "b"},
+		},
+		{
+			"ints",
+			validate.Ints([]int{1, 2}),
+			[]interface{}{1.0, 2.0},
+		},
+		{
+			"float64s",
+			validate.Float64s([]float64{1.5, 2.5}),
+			[]interface{}{1.5, 2.5},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.validate(tt.valid))
+			require.Error(t, tt.validate(tt.valid[:1]))
+			require.Error(t, tt.validate(append(tt.valid, tt.valid[0])))
+			require.Error(t, tt.validate([]interface{}{}))
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := validate.LoadSchema("testdata/record.schema.json")
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		record string
+		valid  bool
+	}{
+		{`{"time":"2006-01-02T15:04:05Z","level":"info","message":"m"}`, true},
+		{`{"time":"2006-01-02T15:04:05Z","level":"info","message":"m",` +
+			`"error":"e","n":1,"l":[1]}`, true},
+		{`{"time":"yesterday","level":"info","message":"m"}`, false},
+		{`{"time":"2006-01-02T15:04:05Z","level":"fatal","message":"m"}`, false},
+		{`{"time":"2006-01-02T15:04:05Z","level":"info"}`, false},
+		{`{"time":"2006-01-02T15:04:05Z","level":"info","message":"m",` +
+			`"error":{}}`, false},
+		{`{"time":"2006-01-02T15:04:05Z","level":"info","message":"m",` +
+			`"n":null}`, false},
+	} {
+		report, err := validate.Records(
+			strings.NewReader(tt.record),
+			schema.Validate,
+		)
+		require.NoError(t, err)
+		require.Equal(t, 1, report.Records)
+		if tt.valid {
+			require.Zero(t, report.Invalid, tt.record)
+			require.NoError(t, report.FirstErr, tt.record)
+		} else {
+			require.Equal(t, 1, report.Invalid, tt.record)
+			require.Error(t, report.FirstErr, tt.record)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	report, err := validate.Records(
+		strings.NewReader("{\"a\":1}\n{\"b\":2}\n{\"a\":3}\n"),
+		validate.FV{"a": validate.Number}.Validate,
+	)
+	require.NoError(t, err)
+	require.Equal(t, validate.Report{
+		Records:  3,
+		Invalid:  1,
+		FirstErr: report.FirstErr,
+	}, report)
+	require.Error(t, report.FirstErr)
+
+	report, err = validate.Records(
+		strings.NewReader("{\"a\":1}\n{\"a\":\n"),
+		validate.FV{"a": validate.Number}.Validate,
+	)
+	require.Error(t, err)
+	require.Equal(t, 1, report.Records)
+}
+
+func TestLines(t *testing.T) {
+	report, err := validate.Lines(
+		strings.NewReader(
+			"{\"a\":1}\n{\"a\":2{\"a\":3}\n}\n\n{\"b\":4}\n{\"a\":5}\n",
+		),
+		validate.FV{"a": validate.Number}.Validate,
+	)
+	require.NoError(t, err)
+	require.Equal(t, validate.LineReport{
+		Lines:     5,
+		Corrupted: 2,
+		Invalid:   1,
+		FirstErr:  report.FirstErr,
+	}, report)
+	require.ErrorContains(t, report.FirstErr, "line 2")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/globusdigital/logbench/validate"
+	"github.com/olekukonko/tablewriter"
+)
+
+// validationTarget is the maximum number of logs written
+// per logger and operation during a validation pass
+const validationTarget = 1000
+
+// schemaResult is the result of the validation pass
+// of a particular logger and operation
+type schemaResult struct {
+	Logger    string
+	Operation string
+	Report    validate.Report
+
+	// Err is set if the output couldn't be decoded
+	Err error
+}
+
+// Failed returns true if a record is invalid or malformed
+func (r schemaResult) Failed() bool {
+	return r.Err != nil || r.Report.Invalid > 0
+}
+
+// validateSchema runs each operation of each logger writing up to
+// validationTarget logs and validates every record against the schema.
+// Unsupported logger/operation pairs are skipped
+func validateSchema(
+	loggers []string,
+	operations []string,
+	conf benchmark.Config,
+	schema *validate.Schema,
+	target uint64,
+	concWriters uint,
+) ([]schemaResult, error) {
+	if conf.Encoding != benchmark.EncodingJSON {
+		return nil, fmt.Errorf(
+			"%w: %s (validation requires json)",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+	if target > validationTarget {
+		target = validationTarget
+	}
+
+	var results []schemaResult
+	for _, loggerName := range loggers {
+		newAdapter, ok := adapters[loggerName]
+		if !ok {
+			return nil, fmt.Errorf("no adapter for logger %q", loggerName)
+		}
+		for _, operation := range operations {
+			buf := new(SyncBuffer)
+			bench, err := benchmark.New(buf, operation, newAdapter, conf)
+			if isUnsupported(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("adapter %q init: %w", loggerName, err)
+			}
+			if s := bench.Run(target, concWriters, nil); s.FlushErr != nil {
+				return nil, fmt.Errorf(
+					"flushing %q: %w",
+					loggerName,
+					s.FlushErr,
+				)
+			}
+
+			report, err := validate.Records(buf, schema.Validate)
+			results = append(results, schemaResult{
+				Logger:    loggerName,
+				Operation: operation,
+				Report:    report,
+				Err:       err,
+			})
+		}
+	}
+	return results, nil
+}
+
+// printSchemaResults prints the results of the validation pass
+func printSchemaResults(results []schemaResult) {
+	tb := tablewriter.NewWriter(os.Stdout)
+	tb.SetHeader([]string{
+		"logger",
+		"operation",
+		"records",
+		"invalid",
+		"first error",
+	})
+	tb.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, r := range results {
+		firstErr := ""
+		switch {
+		case r.Err != nil:
+			firstErr = r.Err.Error()
+		case r.Report.FirstErr != nil:
+			firstErr = r.Report.FirstErr.Error()
+		}
+		tb.Append([]string{
+			r.Logger,
+			r.Operation,
+			strconv.Itoa(r.Report.Records),
+			strconv.Itoa(r.Report.Invalid),
+			firstErr,
+		})
+	}
+	tb.Render()
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/globusdigital/logbench/benchmark"
+)
+
+// cmdVerify is the name of the command verifying the equivalence
+// of the loggers' output instead of benchmarking them
+const cmdVerify = "verify"
+
+// fieldKind is the normalized type of a decoded JSON value
+type fieldKind string
+
+const (
+	kindNull   fieldKind = "null"
+	kindBool   fieldKind = "bool"
+	kindNumber fieldKind = "number"
+	kindString fieldKind = "string"
+	kindArray  fieldKind = "array"
+	kindObject fieldKind = "object"
+
+	// kindTime is the kind of strings holding an RFC 3339 timestamp,
+	// timestamps are normalized regardless of their precision and offset
+	kindTime fieldKind = "time"
+)
+
+// kindOf returns the kind of a value decoded by encoding/json
+func kindOf(v interface{}) fieldKind {
+	switch v := v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return kindTime
+		}
+		return kindString
+	case []interface{}:
+		return kindArray
+	case map[string]interface{}:
+		return kindObject
+	}
+	return fieldKind(fmt.Sprintf("%T", v))
+}
+
+// recordShape maps the field names of a record to the kinds of their values
+type recordShape map[string]fieldKind
+
+func shapeOf(fields map[string]interface{}) recordShape {
+	shape := make(recordShape, len(fields))
+	for name, v := range fields {
+		shape[name] = kindOf(v)
+	}
+	return shape
+}
+
+// captureShape runs the operation once
+// and returns the shape of the first record written
+func captureShape(
+	newAdapter benchmark.NewAdapter,
+	operation string,
+	conf benchmark.Config,
+) (recordShape, error) {
+	buf := new(SyncBuffer)
+	bench, err := benchmark.New(buf, operation, newAdapter, conf)
+	if err != nil {
+		return nil, err
+	}
+	if s := bench.Run(1, 1, nil); s.FlushErr != nil {
+		return nil, fmt.Errorf("flushing: %w", s.FlushErr)
+	}
+
+	var fields map[string]interface{}
+	if err := json.NewDecoder(buf).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+	return shapeOf(fields), nil
+}
+
+// Mismatch problems
+const (
+	problemMissing   = "missing"
+	problemExtra     = "extra"
+	problemType      = "type mismatch"
+	problemUndecoded = "undecodable"
+	problemError     = "error"
+)
+
+// mismatch describes how the record a logger wrote for an operation
+// deviates from the records of the other loggers
+type mismatch struct {
+	Logger    string
+	Operation string
+	Problem   string
+	Field     string
+	Expected  fieldKind
+	Actual    fieldKind
+	Err       error
+}
+
+func (m mismatch) String() string {
+	switch m.Problem {
+	case problemMissing:
+		return fmt.Sprintf(
+			"%s: %s: missing field %q (%s)",
+			m.Operation, m.Logger, m.Field, m.Expected,
+		)
+	case problemExtra:
+		return fmt.Sprintf(
+			"%s: %s: extra field %q (%s)",
+			m.Operation, m.Logger, m.Field, m.Actual,
+		)
+	case problemType:
+		return fmt.Sprintf(
+			"%s: %s: field %q is %s (expected: %s)",
+			m.Operation, m.Logger, m.Field, m.Actual, m.Expected,
+		)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", m.Operation, m.Logger, m.Problem, m.Err)
+}
+
+// majority returns the value most of the given values are equal to,
+// ties are broken by choosing the lowest value
+func majority(values []string) (string, int) {
+	counts := make(map[string]int, len(values))
+	for _, v := range values {
+		counts[v]++
+	}
+	var best string
+	bestCount := 0
+	for v, c := range counts {
+		if c > bestCount || (c == bestCount && v < best) {
+			best, bestCount = v, c
+		}
+	}
+	return best, bestCount
+}
+
+// compareShapes compares the record shapes of an operation by logger.
+// A field is expected if the majority of the loggers write it
+// and it's expected to be of the kind most of them use.
+// Variants of a logger only vote once, through the logger they're based on
+// if it's compared and through the first variant otherwise
+func compareShapes(operation string, shapes map[string]recordShape) []mismatch {
+	loggers := make([]string, 0, len(shapes))
+	fieldSet := map[string]struct{}{}
+	for loggerName, shape := range shapes {
+		loggers = append(loggers, loggerName)
+		for field := range shape {
+			fieldSet[field] = struct{}{}
+		}
+	}
+	sort.Strings(loggers)
+
+	// voters maps the names of the base loggers
+	// to the names of the loggers voting for them
+	voters := make(map[string]string, len(loggers))
+	for _, loggerName := range loggers {
+		base := baseLogger(loggerName)
+		if _, ok := voters[base]; !ok || loggerName == base {
+			voters[base] = loggerName
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var mismatches []mismatch
+	for _, field := range fields {
+		var kinds []string
+		for _, loggerName := range voters {
+			if kind, ok := shapes[loggerName][field]; ok {
+				kinds = append(kinds, string(kind))
+			}
+		}
+		expectedKind, _ := majority(kinds)
+		expected := fieldKind(expectedKind)
+		present := len(kinds)*2 > len(voters)
+
+		for _, loggerName := range loggers {
+			kind, ok := shapes[loggerName][field]
+			switch {
+			case !ok && present:
+				mismatches = append(mismatches, mismatch{
+					Logger:    loggerName,
+					Operation: operation,
+					Problem:   problemMissing,
+					Field:     field,
+					Expected:  expected,
+				})
+			case ok && !present:
+				mismatches = append(mismatches, mismatch{
+					Logger:    loggerName,
+					Operation: operation,
+					Problem:   problemExtra,
+					Field:     field,
+					Actual:    kind,
+				})
+			case ok && kind != expected:
+				mismatches = append(mismatches, mismatch{
+					Logger:    loggerName,
+					Operation: operation,
+					Problem:   problemType,
+					Field:     field,
+					Expected:  expected,
+					Actual:    kind,
+				})
+			}
+		}
+	}
+	return mismatches
+}
+
+// verify runs each operation once per logger and compares the shapes
+// of the records. Unsupported logger/operation pairs are skipped
+func verify(
+	loggers []string,
+	operations []string,
+	conf benchmark.Config,
+) ([]mismatch, error) {
+	if conf.Encoding != benchmark.EncodingJSON {
+		return nil, fmt.Errorf(
+			"%w: %s (verify requires json)",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+
+	var mismatches []mismatch
+	for _, operation := range operations {
+		shapes := make(map[string]recordShape, len(loggers))
+		for _, loggerName := range loggers {
+			newAdapter, ok := adapters[loggerName]
+			if !ok {
+				return nil, fmt.Errorf("no adapter for logger %q", loggerName)
+			}
+			shape, err := captureShape(newAdapter, operation, conf)
+			var jsonErr *json.SyntaxError
+			switch {
+			case isUnsupported(err):
+				continue
+			case errors.As(err, &jsonErr):
+				mismatches = append(mismatches, mismatch{
+					Logger:    loggerName,
+					Operation: operation,
+					Problem:   problemUndecoded,
+					Err:       err,
+				})
+				continue
+			case err != nil:
+				mismatches = append(mismatches, mismatch{
+					Logger:    loggerName,
+					Operation: operation,
+					Problem:   problemError,
+					Err:       err,
+				})
+				continue
+			}
+			shapes[loggerName] = shape
+		}
+		mismatches = append(mismatches, compareShapes(operation, shapes)...)
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"github.com/stretchr/testify/require"
+)
+
+// shapeDeviations maps logger names to the operations the logger is known
+// to write differently shaped records for and the according reason
+var shapeDeviations = map[string]map[string]string{
+	"zap": {
+		benchmark.LogOperationInfoWithErrorStack: "zap writes the stack " +
+			"trace of github.com/pkg/errors errors to errorVerbose",
+	},
+}
+
+// extraFields maps logger names to the fields the logger is known
+// to add to the records of all operations and the according reason
+var extraFields = map[string]map[string]string{
+	"zap": {
+		"caller": "zap annotates the records with the caller",
+	},
+}
+
+func TestCompareShapes(t *testing.T) {
+	shapes := map[string]recordShape{
+		"a": {"time": kindTime, "message": kindString, "n": kindNumber},
+		"b": {"time": kindTime, "message": kindString, "n": kindString},
+		"c": {"time": kindTime, "n": kindNumber, "x": kindBool},
+	}
+	require.Equal(t, []mismatch{
+		{
+			Logger:    "c",
+			Operation: "op",
+			Problem:   problemMissing,
+			Field:     "message",
+			Expected:  kindString,
+		},
+		{
+			Logger:    "b",
+			Operation: "op",
+			Problem:   problemType,
+			Field:     "n",
+			Expected:  kindNumber,
+			Actual:    kindString,
+		},
+		{
+			Logger:    "c",
+			Operation: "op",
+			Problem:   problemExtra,
+			Field:     "x",
+			Actual:    kindBool,
+		},
+	}, compareShapes("op", shapes))
+}
+
+func TestCompareShapesVariants(t *testing.T) {
+	// Variants don't outvote the other loggers
+	shapes := map[string]recordShape{
+		"zap":          {"message": kindString, "caller": kindString},
+		"zap-buffered": {"message": kindString, "caller": kindString},
+		"logrus":       {"message": kindString},
+	}
+	require.Equal(t, []mismatch{
+		{
+			Logger:    "zap",
+			Operation: "op",
+			Problem:   problemExtra,
+			Field:     "caller",
+			Actual:    kindString,
+		},
+		{
+			Logger:    "zap-buffered",
+			Operation: "op",
+			Problem:   problemExtra,
+			Field:     "caller",
+			Actual:    kindString,
+		},
+	}, compareShapes("op", shapes))
+}
+
+func TestKindOf(t *testing.T) {
+	for input, expected := range map[string]fieldKind{
+		`null`:                            kindNull,
+		`true`:                            kindBool,
+		`1.5`:                             kindNumber,
+		`"text"`:                          kindString,
+		`"2006-01-02T15:04:05Z"`:          kindTime,
+		`"2006-01-02T15:04:05.123+07:00"`: kindTime,
+		`[1]`:                             kindArray,
+		`{"a":1}`:                         kindObject,
+	} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(input), &v))
+		require.Equal(t, expected, kindOf(v), input)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	// Only loggers writing JSON can be compared
+	var loggers []string
+	for loggerName := range adapters {
+		if _, ok := decoders[loggerName]; !ok {
+			loggers = append(loggers, loggerName)
+		}
+	}
+	sort.Strings(loggers)
+
+	mismatches, err := verify(
+		loggers,
+		operationsAll,
+		benchmark.DefaultConfig(),
+	)
+	require.NoError(t, err)
+
+	for _, m := range mismatches {
+		base := baseLogger(m.Logger)
+		if _, ok := shapeDeviations[base][m.Operation]; ok {
+			continue
+		}
+		if _, ok := extraFields[base][m.Field]; ok && m.Problem == problemExtra {
+			continue
+		}
+		if m.Problem == problemUndecoded {
+			if _, ok := invalidJSON[base][m.Operation]; ok {
+				continue
+			}
+		}
+		t.Error(m)
+	}
+}
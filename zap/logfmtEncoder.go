@@ -0,0 +1,530 @@
+package zap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/globusdigital/logbench/benchmark"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtBufferPool = buffer.NewPool()
+
+var logfmtEncoderPool = sync.Pool{New: func() interface{} {
+	return new(logfmtEncoder)
+}}
+
+// logfmtEncoder implements zapcore.Encoder by writing logfmt lines
+// of space separated key=value pairs. Arrays are written as [a b c]
+// and objects as {k=v}, both quoted if necessary, similar to what
+// logrus' TextFormatter does
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf       *buffer.Buffer
+	start     int
+	namespace string
+	arr       logfmtArrayEncoder
+}
+
+func newLogfmtEncoder(conf zapcore.EncoderConfig) *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: &conf,
+		buf:           logfmtBufferPool.Get(),
+	}
+}
+
+func (e *logfmtEncoder) clone() *logfmtEncoder {
+	c := logfmtEncoderPool.Get().(*logfmtEncoder)
+	c.EncoderConfig = e.EncoderConfig
+	c.buf = logfmtBufferPool.Get()
+	c.start = 0
+	c.namespace = e.namespace
+	return c
+}
+
+// Clone implements the zapcore.Encoder interface
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	c := e.clone()
+	_, _ = c.buf.Write(e.buf.Bytes())
+	return c
+}
+
+// EncodeEntry implements the zapcore.Encoder interface
+func (e *logfmtEncoder) EncodeEntry(
+	ent zapcore.Entry,
+	fields []zapcore.Field,
+) (*buffer.Buffer, error) {
+	final := e.clone()
+	final.namespace = ""
+
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" {
+		final.addKey(final.LevelKey)
+		cur := final.buf.Len()
+		if final.EncodeLevel != nil {
+			final.EncodeLevel(ent.Level, final.value())
+		}
+		if cur == final.buf.Len() {
+			appendLogfmtString(final.buf, ent.Level.String())
+		}
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey(final.NameKey)
+		cur := final.buf.Len()
+		if final.EncodeName != nil {
+			final.EncodeName(ent.LoggerName, final.value())
+		}
+		if cur == final.buf.Len() {
+			appendLogfmtString(final.buf, ent.LoggerName)
+		}
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" {
+			final.addKey(final.CallerKey)
+			cur := final.buf.Len()
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(ent.Caller, final.value())
+			}
+			if cur == final.buf.Len() {
+				appendLogfmtString(final.buf, ent.Caller.String())
+			}
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	// Append the context and the fields
+	if e.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		_, _ = final.buf.Write(e.buf.Bytes())
+	}
+	final.namespace = e.namespace
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.namespace = ""
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+
+	buf := final.buf
+	final.buf = nil
+	final.arr = logfmtArrayEncoder{}
+	logfmtEncoderPool.Put(final)
+	return buf, nil
+}
+
+// value returns an encoder writing a single value
+func (e *logfmtEncoder) value() *logfmtArrayEncoder {
+	e.arr = logfmtArrayEncoder{buf: e.buf, conf: e.EncoderConfig}
+	return &e.arr
+}
+
+func (e *logfmtEncoder) addKey(key string) {
+	if e.buf.Len() > e.start {
+		e.buf.AppendByte(' ')
+	}
+	appendLogfmtKey(e.buf, e.namespace)
+	appendLogfmtKey(e.buf, key)
+	e.buf.AppendByte('=')
+}
+
+// AddArray implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddArray(
+	key string,
+	marshaler zapcore.ArrayMarshaler,
+) error {
+	tmp := logfmtBufferPool.Get()
+	defer tmp.Free()
+	err := (&logfmtArrayEncoder{
+		buf:  tmp,
+		conf: e.EncoderConfig,
+	}).AppendArray(marshaler)
+	e.addKey(key)
+	appendLogfmtBytes(e.buf, tmp.Bytes())
+	return err
+}
+
+// AddObject implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddObject(
+	key string,
+	marshaler zapcore.ObjectMarshaler,
+) error {
+	tmp := logfmtBufferPool.Get()
+	defer tmp.Free()
+	err := (&logfmtArrayEncoder{
+		buf:  tmp,
+		conf: e.EncoderConfig,
+	}).AppendObject(marshaler)
+	e.addKey(key)
+	appendLogfmtBytes(e.buf, tmp.Bytes())
+	return err
+}
+
+// AddBinary implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddBinary(key string, value []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+// AddByteString implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddByteString(key string, value []byte) {
+	e.addKey(key)
+	appendLogfmtBytes(e.buf, value)
+}
+
+// AddBool implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddBool(key string, value bool) {
+	e.addKey(key)
+	e.buf.AppendBool(value)
+}
+
+// AddComplex128 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddComplex128(key string, value complex128) {
+	e.addKey(key)
+	e.value().AppendComplex128(value)
+}
+
+// AddComplex64 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddComplex64(key string, value complex64) {
+	e.AddComplex128(key, complex128(value))
+}
+
+// AddDuration implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	e.addKey(key)
+	e.value().AppendDuration(value)
+}
+
+// AddFloat64 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddFloat64(key string, value float64) {
+	e.addKey(key)
+	e.buf.AppendFloat(value, 64)
+}
+
+// AddFloat32 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddFloat32(key string, value float32) {
+	e.addKey(key)
+	e.buf.AppendFloat(float64(value), 32)
+}
+
+// AddInt implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddInt(key string, value int) {
+	e.AddInt64(key, int64(value))
+}
+
+// AddInt64 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddInt64(key string, value int64) {
+	e.addKey(key)
+	e.buf.AppendInt(value)
+}
+
+// AddInt32 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddInt32(key string, value int32) {
+	e.AddInt64(key, int64(value))
+}
+
+// AddInt16 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddInt16(key string, value int16) {
+	e.AddInt64(key, int64(value))
+}
+
+// AddInt8 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddInt8(key string, value int8) {
+	e.AddInt64(key, int64(value))
+}
+
+// AddString implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddString(key, value string) {
+	e.addKey(key)
+	appendLogfmtString(e.buf, value)
+}
+
+// AddTime implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddTime(key string, value time.Time) {
+	e.addKey(key)
+	e.value().AppendTime(value)
+}
+
+// AddUint implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUint(key string, value uint) {
+	e.AddUint64(key, uint64(value))
+}
+
+// AddUint64 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUint64(key string, value uint64) {
+	e.addKey(key)
+	e.buf.AppendUint(value)
+}
+
+// AddUint32 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUint32(key string, value uint32) {
+	e.AddUint64(key, uint64(value))
+}
+
+// AddUint16 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUint16(key string, value uint16) {
+	e.AddUint64(key, uint64(value))
+}
+
+// AddUint8 implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUint8(key string, value uint8) {
+	e.AddUint64(key, uint64(value))
+}
+
+// AddUintptr implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddUintptr(key string, value uintptr) {
+	e.AddUint64(key, uint64(value))
+}
+
+// AddReflected implements the zapcore.ObjectEncoder interface
+func (e *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	e.addKey(key)
+	return e.value().AppendReflected(value)
+}
+
+// OpenNamespace implements the zapcore.ObjectEncoder interface
+// by prefixing all subsequently added keys with "key."
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.namespace += key + "."
+}
+
+// logfmtArrayEncoder implements zapcore.ArrayEncoder
+// by writing space separated values
+type logfmtArrayEncoder struct {
+	buf  *buffer.Buffer
+	conf *zapcore.EncoderConfig
+	n    int
+}
+
+func (a *logfmtArrayEncoder) separate() {
+	if a.n > 0 {
+		a.buf.AppendByte(' ')
+	}
+	a.n++
+}
+
+// AppendBool implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendBool(v bool) {
+	a.separate()
+	a.buf.AppendBool(v)
+}
+
+// AppendByteString implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendByteString(v []byte) {
+	a.separate()
+	appendLogfmtBytes(a.buf, v)
+}
+
+// AppendComplex128 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendComplex128(v complex128) {
+	a.separate()
+	a.buf.AppendString(strconv.FormatComplex(v, 'f', -1, 128))
+}
+
+// AppendComplex64 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendComplex64(v complex64) {
+	a.separate()
+	a.buf.AppendString(strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+
+// AppendFloat64 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendFloat64(v float64) {
+	a.separate()
+	a.buf.AppendFloat(v, 64)
+}
+
+// AppendFloat32 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendFloat32(v float32) {
+	a.separate()
+	a.buf.AppendFloat(float64(v), 32)
+}
+
+// AppendInt implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendInt(v int) { a.AppendInt64(int64(v)) }
+
+// AppendInt64 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendInt64(v int64) {
+	a.separate()
+	a.buf.AppendInt(v)
+}
+
+// AppendInt32 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendInt32(v int32) { a.AppendInt64(int64(v)) }
+
+// AppendInt16 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendInt16(v int16) { a.AppendInt64(int64(v)) }
+
+// AppendInt8 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendInt8(v int8) { a.AppendInt64(int64(v)) }
+
+// AppendString implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendString(v string) {
+	a.separate()
+	appendLogfmtString(a.buf, v)
+}
+
+// AppendUint implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUint(v uint) { a.AppendUint64(uint64(v)) }
+
+// AppendUint64 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUint64(v uint64) {
+	a.separate()
+	a.buf.AppendUint(v)
+}
+
+// AppendUint32 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUint32(v uint32) {
+	a.AppendUint64(uint64(v))
+}
+
+// AppendUint16 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUint16(v uint16) {
+	a.AppendUint64(uint64(v))
+}
+
+// AppendUint8 implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUint8(v uint8) { a.AppendUint64(uint64(v)) }
+
+// AppendUintptr implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendUintptr(v uintptr) {
+	a.AppendUint64(uint64(v))
+}
+
+// AppendDuration implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendDuration(v time.Duration) {
+	cur := a.buf.Len()
+	if a.conf.EncodeDuration != nil {
+		a.conf.EncodeDuration(v, a)
+	}
+	if cur == a.buf.Len() {
+		// Fall back to nanoseconds like the JSON encoder does
+		a.AppendInt64(int64(v))
+	}
+}
+
+// AppendTime implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendTime(v time.Time) {
+	cur := a.buf.Len()
+	if a.conf.EncodeTime != nil {
+		a.conf.EncodeTime(v, a)
+	}
+	if cur == a.buf.Len() {
+		// Fall back to nanoseconds since epoch like the JSON encoder does
+		a.AppendInt64(v.UnixNano())
+	}
+}
+
+// AppendArray implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	a.separate()
+	a.buf.AppendByte('[')
+	err := marshaler.MarshalLogArray(&logfmtArrayEncoder{
+		buf:  a.buf,
+		conf: a.conf,
+	})
+	a.buf.AppendByte(']')
+	return err
+}
+
+// AppendObject implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendObject(
+	marshaler zapcore.ObjectMarshaler,
+) error {
+	a.separate()
+	a.buf.AppendByte('{')
+	err := marshaler.MarshalLogObject(&logfmtEncoder{
+		EncoderConfig: a.conf,
+		buf:           a.buf,
+		start:         a.buf.Len(),
+	})
+	a.buf.AppendByte('}')
+	return err
+}
+
+// AppendReflected implements the zapcore.ArrayEncoder interface
+func (a *logfmtArrayEncoder) AppendReflected(value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	a.AppendString(string(b))
+	return nil
+}
+
+// appendLogfmtKey appends key replacing all characters
+// that aren't allowed in logfmt keys by underscores
+func appendLogfmtKey(buf *buffer.Buffer, key string) {
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			buf.AppendByte('_')
+		} else {
+			buf.AppendByte(c)
+		}
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendLogfmtString appends s and quotes it if necessary.
+// Quoted strings are escaped like strconv.Quote does
+// but directly into buf
+func appendLogfmtString(buf *buffer.Buffer, s string) {
+	if !benchmark.LogfmtNeedsQuoting(s) {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		invalid := r == utf8.RuneError && size == 1
+		if r != '"' && r != '\\' && !invalid && strconv.IsPrint(r) {
+			i += size
+			continue
+		}
+		buf.AppendString(s[start:i])
+		switch {
+		case r == '"' || r == '\\':
+			buf.AppendByte('\\')
+			buf.AppendByte(byte(r))
+		case invalid:
+			buf.AppendString(`\x`)
+			buf.AppendByte(hexDigits[s[i]>>4])
+			buf.AppendByte(hexDigits[s[i]&0xf])
+		default:
+			// The escape sequence is quoted by single quotes
+			var tmp [16]byte
+			q := strconv.AppendQuoteRune(tmp[:0], r)
+			_, _ = buf.Write(q[1 : len(q)-1])
+		}
+		i += size
+		start = i
+	}
+	buf.AppendString(s[start:])
+	buf.AppendByte('"')
+}
+
+// appendLogfmtBytes appends b like appendLogfmtString without copying it
+func appendLogfmtBytes(buf *buffer.Buffer, b []byte) {
+	appendLogfmtString(buf, unsafe.String(unsafe.SliceData(b), len(b)))
+}
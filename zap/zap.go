@@ -1,9 +1,9 @@
 package zap
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"time"
 
 	"github.com/globusdigital/logbench/benchmark"
@@ -11,132 +11,144 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func defaultConfig() zap.Config {
-	return zap.Config{
-		Level:             zap.NewAtomicLevelAt(zap.DebugLevel),
-		Encoding:          "json",
-		DisableStacktrace: true,
-		EncoderConfig: zapcore.EncoderConfig{
-			MessageKey:    "message",
-			LevelKey:      "level",
-			TimeKey:       "time",
-			NameKey:       "name",
-			CallerKey:     "caller",
-			StacktraceKey: "stack",
-			EncodeLevel: func(
-				l zapcore.Level,
-				enc zapcore.PrimitiveArrayEncoder,
-			) {
-				switch l {
-				case zapcore.DebugLevel:
-					enc.AppendString("debug")
-				case zapcore.InfoLevel:
-					enc.AppendString("info")
-				case zapcore.WarnLevel:
-					enc.AppendString("warning")
-				case zapcore.ErrorLevel:
-					enc.AppendString("error")
-				case zapcore.DPanicLevel:
-					enc.AppendString("dpanic")
-				case zapcore.PanicLevel:
-					enc.AppendString("panic")
-				case zapcore.FatalLevel:
-					enc.AppendString("fatal")
-				}
-			},
-			EncodeTime: func(
-				tm time.Time,
-				enc zapcore.PrimitiveArrayEncoder,
-			) {
-				enc.AppendString(tm.Format(benchmark.TimeFormat))
-			},
-			EncodeCaller: zapcore.ShortCallerEncoder,
+func defaultEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:    "message",
+		LevelKey:      "level",
+		TimeKey:       "time",
+		NameKey:       "name",
+		CallerKey:     "caller",
+		StacktraceKey: "stack",
+		EncodeLevel: func(
+			l zapcore.Level,
+			enc zapcore.PrimitiveArrayEncoder,
+		) {
+			switch l {
+			case zapcore.DebugLevel:
+				enc.AppendString("debug")
+			case zapcore.InfoLevel:
+				enc.AppendString("info")
+			case zapcore.WarnLevel:
+				enc.AppendString("warning")
+			case zapcore.ErrorLevel:
+				enc.AppendString("error")
+			case zapcore.DPanicLevel:
+				enc.AppendString("dpanic")
+			case zapcore.PanicLevel:
+				enc.AppendString("panic")
+			case zapcore.FatalLevel:
+				enc.AppendString("fatal")
+			}
 		},
+		EncodeTime:   encodeTime(benchmark.TimeRFC3339Nano),
+		EncodeCaller: zapcore.ShortCallerEncoder,
 	}
 }
 
-func newLogger(out io.ReadWriter, conf zap.Config) (*zap.Logger, error) {
-	if err := zapSink.SetOut(out); err != nil {
-		return nil, fmt.Errorf("setting sink output: %w", err)
+// newEncoder creates the encoder of the given encoding
+func newEncoder(
+	e benchmark.Encoding,
+	conf zapcore.EncoderConfig,
+) (zapcore.Encoder, error) {
+	switch e {
+	case benchmark.EncodingJSON:
+		return zapcore.NewJSONEncoder(conf), nil
+	case benchmark.EncodingConsole:
+		return zapcore.NewConsoleEncoder(conf), nil
+	case benchmark.EncodingLogfmt:
+		return newLogfmtEncoder(conf), nil
 	}
+	return nil, fmt.Errorf("%w: %s", benchmark.ErrUnsupportedEncoding, e)
+}
 
-	if out == os.Stdout {
-		conf.OutputPaths = []string{"stdout"}
-	} else {
-		conf.OutputPaths = []string{"memory://"}
+// encodeTime returns the time encoder of the given time encoding
+func encodeTime(e benchmark.TimeEncoding) zapcore.TimeEncoder {
+	switch e {
+	case benchmark.TimeUnix:
+		return func(tm time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(tm.Unix())
+		}
+	case benchmark.TimeUnixMs:
+		return func(tm time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(tm.UnixMilli())
+		}
 	}
-
-	l, err := conf.Build()
-	if err != nil {
-		return nil, fmt.Errorf("building zap config: %w", err)
+	layout := e.Layout()
+	return func(tm time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(tm.Format(layout))
 	}
-	return l, nil
 }
 
-func newInfo(out io.ReadWriter) (benchmark.FnInfo, error) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
-	}
-	return func(msg string) {
-		l.Info(msg)
-	}, nil
-}
+// adapter is the zap logger adapter
+type adapter struct {
+	l *zap.Logger
 
-func newInfoFmt(out io.ReadWriter) (benchmark.FnInfoFmt, error) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
-	}
-	return func(msg string, data int) {
-		l.Info(fmt.Sprintf(msg, data))
-	}, nil
+	// with10Exist, sampled and hooked are derived from l
+	with10Exist *zap.Logger
+	sampled     *zap.Logger
+	hooked      *zap.Logger
+	ctx         context.Context
+
+	// ws is the buffered write syncer, nil if the output isn't buffered
+	ws *zapcore.BufferedWriteSyncer
 }
 
-func newInfoWithErrorStack(out io.ReadWriter) (
-	benchmark.FnInfoWithErrorStack,
-	error,
-) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
-	}
-	return func(msg string, err error) {
-		l.Info(msg, zap.Error(err))
-	}, nil
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+)
+
+// clock is a zapcore.Clock providing the time of the logs
+// through the clock of the benchmark configuration
+type clock func() time.Time
+
+func (c clock) Now() time.Time { return c() }
+
+func (c clock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
 }
 
-func newError(out io.ReadWriter) (benchmark.FnError, error) {
-	l, err := newLogger(out, defaultConfig())
+func newAdapter(
+	out io.ReadWriter,
+	bconf benchmark.Config,
+	buffered bool,
+) (benchmark.Adapter, error) {
+	conf := defaultEncoderConfig()
+	if bconf.TimeEncoding == benchmark.TimeNone {
+		// zap omits the time field if its key is empty
+		conf.TimeKey = ""
+	} else {
+		conf.EncodeTime = encodeTime(bconf.TimeEncoding)
+	}
+	enc, err := newEncoder(bconf.Encoding, conf)
 	if err != nil {
 		return nil, err
 	}
-	return func(msg string) {
-		l.Error(msg)
-	}, nil
-}
 
-func newInfoWith3(out io.ReadWriter) (benchmark.FnInfoWith3, error) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
+	// Every adapter writes to its own output like the other loggers do
+	ws := zapcore.AddSync(out)
+	var buf *zapcore.BufferedWriteSyncer
+	if buffered {
+		buf = &zapcore.BufferedWriteSyncer{WS: ws}
+		ws = buf
 	}
-	return func(msg string, fields *benchmark.Fields3) {
-		l.Info(msg,
-			zap.String(fields.Name1, fields.Value1),
-			zap.Int(fields.Name2, fields.Value2),
-			zap.Float64(fields.Name3, fields.Value3),
-		)
-	}, nil
-}
 
-func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
+	// zap.Config.Build annotates the records with the caller
+	// unlike zap.New, stack traces remain disabled
+	opts := []zap.Option{zap.AddCaller()}
+	if bconf.Clock != nil {
+		opts = append(opts, zap.WithClock(clock(bconf.Clock)))
 	}
-	return func(msg string, fields *benchmark.Fields10) {
-		l.Info(msg,
+	l := zap.New(zapcore.NewCore(enc, ws, zap.DebugLevel), opts...)
+
+	fields := benchmark.NewFields10()
+	return &adapter{
+		l: l,
+		with10Exist: l.With(
 			zap.String(fields.Name1, fields.Value1),
 			zap.String(fields.Name2, fields.Value2),
 			zap.String(fields.Name3, fields.Value3),
@@ -147,20 +159,72 @@ func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
 			zap.Strings(fields.Name8, fields.Value8),
 			zap.Ints(fields.Name9, fields.Value9),
 			zap.Float64s(fields.Name10, fields.Value10),
-		)
+		),
+		sampled: l.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			// Emit the first log and every SampleRate'th log thereafter,
+			// the tick is long enough to never reset the counters
+			return zapcore.NewSamplerWithOptions(
+				c,
+				24*time.Hour,
+				1,
+				benchmark.SampleRate,
+			)
+		})),
+		hooked: l.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return hostnameCore{c}
+		})),
+		ctx: context.WithValue(benchmark.NewTraceContext(), ctxKeyLogger{}, l),
+		ws:  buf,
 	}, nil
 }
 
-func newInfoWith10Exist(out io.ReadWriter) (
-	benchmark.FnInfoWith10Exist,
-	error,
-) {
-	l, err := newLogger(out, defaultConfig())
-	if err != nil {
-		return nil, err
+func zapFields(fields benchmark.FieldsN) []zap.Field {
+	zf := make([]zap.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			zf[i] = zap.String(f.Name, f.String)
+		case benchmark.FieldTypeInt:
+			zf[i] = zap.Int(f.Name, f.Int)
+		case benchmark.FieldTypeFloat64:
+			zf[i] = zap.Float64(f.Name, f.Float64)
+		case benchmark.FieldTypeBool:
+			zf[i] = zap.Bool(f.Name, f.Bool)
+		case benchmark.FieldTypeDuration:
+			zf[i] = zap.Duration(f.Name, f.Duration)
+		case benchmark.FieldTypeTime:
+			zf[i] = zap.Time(f.Name, f.Time)
+		}
 	}
-	fields := benchmark.NewFields10()
-	l = l.With(
+	return zf
+}
+
+func (a *adapter) Info(msg string) {
+	a.l.Info(msg)
+}
+
+func (a *adapter) InfoFmt(msg string, data int) {
+	a.l.Info(fmt.Sprintf(msg, data))
+}
+
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	a.l.Info(msg, zap.Error(err))
+}
+
+func (a *adapter) Error(msg string) {
+	a.l.Error(msg)
+}
+
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	a.l.Info(msg,
+		zap.String(fields.Name1, fields.Value1),
+		zap.Int(fields.Name2, fields.Value2),
+		zap.Float64(fields.Name3, fields.Value3),
+	)
+}
+
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	a.l.Info(msg,
 		zap.String(fields.Name1, fields.Value1),
 		zap.String(fields.Name2, fields.Value2),
 		zap.String(fields.Name3, fields.Value3),
@@ -172,20 +236,110 @@ func newInfoWith10Exist(out io.ReadWriter) (
 		zap.Ints(fields.Name9, fields.Value9),
 		zap.Float64s(fields.Name10, fields.Value10),
 	)
-	return func(msg string) {
-		l.Info(msg)
-	}, nil
 }
 
-// Setup defines the zap logger setup
-func Setup() benchmark.Setup {
-	return benchmark.Setup{
-		Info:               newInfo,
-		InfoFmt:            newInfoFmt,
-		InfoWithErrorStack: newInfoWithErrorStack,
-		Error:              newError,
-		InfoWith3:          newInfoWith3,
-		InfoWith10:         newInfoWith10,
-		InfoWith10Exist:    newInfoWith10Exist,
+func (a *adapter) InfoWith10Exist(msg string) {
+	a.with10Exist.Info(msg)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	a.l.Info(msg, zapFields(fields)...)
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
+) {
+	c := a.l.With(zapFields(fields)...)
+	for i := 0; i < messages; i++ {
+		c.Info(msg)
 	}
 }
+
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	a.l.Info(msg,
+		zap.Time(fields.Name1, fields.Value1),
+		zap.Duration(fields.Name2, fields.Value2),
+		zap.ByteString(fields.Name3, fields.Value3),
+		zap.Binary(fields.Name4, fields.Value4),
+		zap.Stringer(fields.Name5, fields.Value5),
+		zap.Errors(fields.Name6, fields.Value6),
+	)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	a.sampled.Info(msg)
+}
+
+// hostnameCore is a core wrapper adding the hostname field
+type hostnameCore struct{ zapcore.Core }
+
+func (c hostnameCore) With(fields []zapcore.Field) zapcore.Core {
+	return hostnameCore{c.Core.With(fields)}
+}
+
+func (c hostnameCore) Check(
+	e zapcore.Entry,
+	ce *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c hostnameCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(
+		e,
+		append(fields, zap.String(benchmark.FieldHostname, benchmark.Hostname)),
+	)
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	a.hooked.Info(msg)
+}
+
+type ctxKeyLogger struct{}
+
+func fromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(ctxKeyLogger{}).(*zap.Logger); ok {
+		return l
+	}
+	return zap.NewNop()
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	fromContext(a.ctx).Info(
+		msg,
+		zap.String(
+			benchmark.FieldTraceID,
+			benchmark.TraceIDFromContext(a.ctx),
+		),
+	)
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	if err := a.l.Sync(); err != nil {
+		return 0, err
+	}
+	if a.ws != nil {
+		// BufferedWriteSyncer blocks instead of dropping logs
+		return 0, a.ws.Stop()
+	}
+	return 0, nil
+}
+
+// New creates a new zap based logger adapter
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, false)
+}
+
+// NewBuffered creates a new zap based logger adapter
+// buffering the output through a zapcore.BufferedWriteSyncer
+func NewBuffered(
+	out io.ReadWriter,
+	conf benchmark.Config,
+) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, true)
+}
@@ -0,0 +1,6 @@
+//go:build !binary_log
+
+package zerolog
+
+// binaryLog is set if zerolog writes CBOR instead of JSON
+const binaryLog = false
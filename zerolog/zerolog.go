@@ -1,69 +1,144 @@
 package zerolog
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/globusdigital/logbench/benchmark"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/diode"
 )
 
-func init() {
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-}
+// diodeSize is the number of logs buffered by the diode writer
+const diodeSize = 1000
 
-func newLogger(out io.ReadWriter) zerolog.Logger {
-	// Initialize logger
-	return zerolog.New(out).With().Timestamp().Logger()
-}
+// adapter is the zerolog logger adapter
+type adapter struct {
+	l zerolog.Logger
 
-func newInfo(out io.ReadWriter) (benchmark.FnInfo, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Info().Msg(msg)
-	}, nil
+	// with10Exist, sampled and hooked are derived from l
+	with10Exist zerolog.Logger
+	sampled     zerolog.Logger
+	hooked      zerolog.Logger
+	ctx         context.Context
+
+	flush benchmark.FnFlush
 }
 
-func newInfoFmt(out io.ReadWriter) (benchmark.FnInfoFmt, error) {
-	l := newLogger(out)
-	return func(msg string, data int) {
-		l.Info().Msgf(msg, data)
-	}, nil
+var (
+	_ benchmark.FieldsLogger  = (*adapter)(nil)
+	_ benchmark.ChildLogger   = (*adapter)(nil)
+	_ benchmark.ContextLogger = (*adapter)(nil)
+	_ benchmark.TypesLogger   = (*adapter)(nil)
+	_ benchmark.SampledLogger = (*adapter)(nil)
+	_ benchmark.HookLogger    = (*adapter)(nil)
+	_ benchmark.BinaryLogger  = (*adapter)(nil)
+)
+
+func init() {
+	Configure(benchmark.DefaultConfig())
 }
 
-func newInfoWithErrorStack(out io.ReadWriter) (
-	benchmark.FnInfoWithErrorStack, error,
-) {
-	l := newLogger(out)
-	return func(msg string, err error) {
-		l := l.With().Err(err).Logger()
-		l.Info().Msg(msg)
-	}, nil
+// timeFieldFormat returns zerolog's time field format
+// of the given time encoding
+func timeFieldFormat(e benchmark.TimeEncoding) string {
+	switch e {
+	case benchmark.TimeUnix:
+		return zerolog.TimeFormatUnix
+	case benchmark.TimeUnixMs:
+		return zerolog.TimeFormatUnixMs
+	case benchmark.TimeNone:
+		// Still applies to the time fields
+		return benchmark.TimeFormat
+	}
+	return e.Layout()
 }
 
-func newError(out io.ReadWriter) (benchmark.FnError, error) {
-	l := newLogger(out)
-	return func(msg string) {
-		l.Error().Msg(msg)
-	}, nil
+// Configure sets zerolog's global time field format and timestamp function
+// according to the time encoding and the clock of the configuration.
+// They're shared by all zerolog adapters, Configure must thus be called
+// before creating adapters and not while adapters are in use
+func Configure(conf benchmark.Config) {
+	zerolog.TimeFieldFormat = timeFieldFormat(conf.TimeEncoding)
+	zerolog.TimestampFunc = time.Now
+	if conf.Clock != nil {
+		zerolog.TimestampFunc = conf.Clock
+	}
 }
 
-func newInfoWith3(out io.ReadWriter) (benchmark.FnInfoWith3, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields3) {
-		l := l.With().
-			Str(fields.Name1, fields.Value1).
-			Int(fields.Name2, fields.Value2).
-			Float64(fields.Name3, fields.Value3).
-			Logger()
-		l.Info().Msg(msg)
-	}, nil
+// newDiodeWriter creates a diode writer
+// and the function flushing and closing it
+func newDiodeWriter(out io.ReadWriter) (io.Writer, benchmark.FnFlush) {
+	dropped := new(uint64)
+	w := diode.NewWriter(out, diodeSize, 0, func(missed int) {
+		atomic.AddUint64(dropped, uint64(missed))
+	})
+	return w, func() (uint64, error) {
+		err := w.Close()
+		return atomic.LoadUint64(dropped), err
+	}
 }
 
-func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
-	l := newLogger(out)
-	return func(msg string, fields *benchmark.Fields10) {
-		l := l.With().
+func newAdapter(
+	out io.ReadWriter,
+	conf benchmark.Config,
+	withDiode bool,
+) (benchmark.Adapter, error) {
+	switch conf.Encoding {
+	case benchmark.EncodingJSON, benchmark.EncodingConsole:
+	default:
+		return nil, fmt.Errorf(
+			"%w: %s",
+			benchmark.ErrUnsupportedEncoding,
+			conf.Encoding,
+		)
+	}
+	if f := timeFieldFormat(conf.TimeEncoding); zerolog.TimeFieldFormat != f {
+		return nil, fmt.Errorf(
+			"zerolog time field format %q doesn't match %s, "+
+				"zerolog.Configure wasn't called",
+			zerolog.TimeFieldFormat,
+			conf.TimeEncoding,
+		)
+	}
+
+	var w io.Writer = out
+	var flush benchmark.FnFlush
+	if withDiode {
+		w, flush = newDiodeWriter(out)
+	}
+	if conf.Encoding == benchmark.EncodingConsole {
+		cw := zerolog.ConsoleWriter{
+			Out:        w,
+			NoColor:    true,
+			TimeFormat: conf.TimeEncoding.Layout(),
+		}
+		switch conf.TimeEncoding {
+		case benchmark.TimeUnix, benchmark.TimeUnixMs:
+			// Write Unix timestamps as they are
+			cw.FormatTimestamp = func(i interface{}) string {
+				return fmt.Sprint(i)
+			}
+		case benchmark.TimeNone:
+			cw.PartsExclude = []string{zerolog.TimestampFieldName}
+		}
+		w = cw
+	}
+
+	// Initialize logger
+	l := zerolog.New(w)
+	if conf.TimeEncoding != benchmark.TimeNone {
+		// The timestamp is formatted and provided according to Configure
+		l = l.With().Timestamp().Logger()
+	}
+
+	fields := benchmark.NewFields10()
+	return &adapter{
+		l: l,
+		with10Exist: l.With().
 			Str(fields.Name1, fields.Value1).
 			Str(fields.Name2, fields.Value2).
 			Str(fields.Name3, fields.Value3).
@@ -74,18 +149,62 @@ func newInfoWith10(out io.ReadWriter) (benchmark.FnInfoWith10, error) {
 			Strs(fields.Name8, fields.Value8).
 			Ints(fields.Name9, fields.Value9).
 			Floats64(fields.Name10, fields.Value10).
-			Logger()
-		l.Info().Msg(msg)
+			Logger(),
+		sampled: l.Sample(&zerolog.BasicSampler{N: benchmark.SampleRate}),
+		hooked:  l.Hook(hostnameHook{}),
+		ctx:     l.WithContext(benchmark.NewTraceContext()),
+		flush:   flush,
 	}, nil
 }
 
-func newInfoWith10Exist(out io.ReadWriter) (
-	benchmark.FnInfoWith10Exist,
-	error,
-) {
-	fields := benchmark.NewFields10()
-	l := newLogger(out)
-	l = l.With().
+func withFields(c zerolog.Context, fields benchmark.FieldsN) zerolog.Context {
+	for _, f := range fields {
+		switch f.Type {
+		case benchmark.FieldTypeString:
+			c = c.Str(f.Name, f.String)
+		case benchmark.FieldTypeInt:
+			c = c.Int(f.Name, f.Int)
+		case benchmark.FieldTypeFloat64:
+			c = c.Float64(f.Name, f.Float64)
+		case benchmark.FieldTypeBool:
+			c = c.Bool(f.Name, f.Bool)
+		case benchmark.FieldTypeDuration:
+			c = c.Dur(f.Name, f.Duration)
+		case benchmark.FieldTypeTime:
+			c = c.Time(f.Name, f.Time)
+		}
+	}
+	return c
+}
+
+func (a *adapter) Info(msg string) {
+	a.l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoFmt(msg string, data int) {
+	a.l.Info().Msgf(msg, data)
+}
+
+func (a *adapter) InfoWithErrorStack(msg string, err error) {
+	l := a.l.With().Err(err).Logger()
+	l.Info().Msg(msg)
+}
+
+func (a *adapter) Error(msg string) {
+	a.l.Error().Msg(msg)
+}
+
+func (a *adapter) InfoWith3(msg string, fields *benchmark.Fields3) {
+	l := a.l.With().
+		Str(fields.Name1, fields.Value1).
+		Int(fields.Name2, fields.Value2).
+		Float64(fields.Name3, fields.Value3).
+		Logger()
+	l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoWith10(msg string, fields *benchmark.Fields10) {
+	l := a.l.With().
 		Str(fields.Name1, fields.Value1).
 		Str(fields.Name2, fields.Value2).
 		Str(fields.Name3, fields.Value3).
@@ -97,20 +216,84 @@ func newInfoWith10Exist(out io.ReadWriter) (
 		Ints(fields.Name9, fields.Value9).
 		Floats64(fields.Name10, fields.Value10).
 		Logger()
-	return func(msg string) {
-		l.Info().Msg(msg)
-	}, nil
+	l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoWith10Exist(msg string) {
+	a.with10Exist.Info().Msg(msg)
+}
+
+func (a *adapter) InfoWithN(msg string, fields benchmark.FieldsN) {
+	l := withFields(a.l.With(), fields).Logger()
+	l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoChildLogger(
+	msg string,
+	fields benchmark.FieldsN,
+	messages int,
+) {
+	c := withFields(a.l.With(), fields).Logger()
+	for i := 0; i < messages; i++ {
+		c.Info().Msg(msg)
+	}
 }
 
-// Setup initializes the zerolog based logger
-func Setup() benchmark.Setup {
-	return benchmark.Setup{
-		Info:               newInfo,
-		InfoFmt:            newInfoFmt,
-		InfoWithErrorStack: newInfoWithErrorStack,
-		Error:              newError,
-		InfoWith3:          newInfoWith3,
-		InfoWith10:         newInfoWith10,
-		InfoWith10Exist:    newInfoWith10Exist,
+func (a *adapter) InfoWithTypes(msg string, fields *benchmark.FieldsTypes) {
+	l := a.l.With().
+		Time(fields.Name1, fields.Value1).
+		Dur(fields.Name2, fields.Value2).
+		Bytes(fields.Name3, fields.Value3).
+		Hex(fields.Name4, fields.Value4).
+		IPAddr(fields.Name5, fields.Value5).
+		Errs(fields.Name6, fields.Value6).
+		Logger()
+	l.Info().Msg(msg)
+}
+
+func (a *adapter) InfoSampled(msg string) {
+	a.sampled.Info().Msg(msg)
+}
+
+// hostnameHook is a hook adding the hostname field
+type hostnameHook struct{}
+
+func (hostnameHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	e.Str(benchmark.FieldHostname, benchmark.Hostname)
+}
+
+func (a *adapter) InfoWithHook(msg string) {
+	a.hooked.Info().Msg(msg)
+}
+
+func (a *adapter) InfoWithContext(msg string) {
+	zerolog.Ctx(a.ctx).Info().
+		Str(benchmark.FieldTraceID, benchmark.TraceIDFromContext(a.ctx)).
+		Msg(msg)
+}
+
+func (a *adapter) Binary() bool {
+	// Every event is written at once, the diode writer doesn't merge them
+	return binaryLog
+}
+
+func (a *adapter) Flush() (uint64, error) {
+	if a.flush == nil {
+		return 0, nil
 	}
+	return a.flush()
+}
+
+// New creates a new zerolog based logger adapter
+func New(out io.ReadWriter, conf benchmark.Config) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, false)
+}
+
+// NewDiode creates a new zerolog based logger adapter
+// writing through a non-blocking diode writer
+func NewDiode(
+	out io.ReadWriter,
+	conf benchmark.Config,
+) (benchmark.Adapter, error) {
+	return newAdapter(out, conf, true)
 }